@@ -13,21 +13,27 @@ type APU struct {
 	pulse1 *Pulse
 }
 
-// NewAPU creates a new APU.
-func NewAPU(mem memory.AddressSpace) *APU {
+// NewAPU creates a new APU owning the sound registers in io.
+func NewAPU(io *memory.IO) *APU {
 	sr := beep.SampleRate(44100)
 	err := speaker.Init(sr, sr.N(time.Second/30))
 	if err != nil {
 		panic(err)
 	}
+	mem := io.Raw()
 	pulse1 := &Pulse{
 		SampleRate: sr,
 		Duty:       memory.NewRegisterWithMask(mem, 0xFF11, 0xC0),
 		Frequency:  memory.NewRegister16WithMask(mem, 0xFF13, 0xFF14, 0x07),
 		Envelope:   memory.NewRegisterWithMask(mem, 0xFF12, 0x07),
 		Volume:     memory.NewRegisterWithMask(mem, 0xFF12, 0xF0),
-		Restart:    memory.NewRegisterBit(mem, 0xFF14, 7),
 	}
+	// Writing bit 7 of NR14 restarts the sound.
+	io.Subscribe(0xFF14, func(v uint8) {
+		if v&0x80 != 0 {
+			pulse1.Trigger()
+		}
+	})
 	return &APU{
 		pulse1: pulse1,
 	}
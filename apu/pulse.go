@@ -15,11 +15,11 @@ type Pulse struct {
 	Envelope  memory.Register
 	Volume    memory.Register
 	Duty      memory.Register
-	Restart   memory.RegisterBit
 
-	waveT time.Duration
-	envT  time.Duration
-	vol   int
+	restart bool
+	waveT   time.Duration
+	envT    time.Duration
+	vol     int
 }
 
 func (p *Pulse) envelopePeriod() time.Duration {
@@ -76,16 +76,21 @@ func (p *Pulse) Stream(samples [][2]float64) (n int, ok bool) {
 		}
 
 		// When the restart flag is set, we need to start the sound again.
-		if p.Restart.Get() {
+		if p.restart {
 			p.envT = 0
 			p.waveT = 0
 			p.vol = int(p.Volume.Get())
-			p.Restart.Set(false)
+			p.restart = false
 		}
 	}
 	return len(samples), true
 }
 
+// Trigger restarts the sound.
+func (p *Pulse) Trigger() {
+	p.restart = true
+}
+
 // Err returns a streaming error which cannot occur.
 func (p *Pulse) Err() error {
 	return nil
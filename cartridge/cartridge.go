@@ -0,0 +1,93 @@
+package cartridge
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/andreaperizzato/gameboy/memory"
+)
+
+// ErrUnsupportedType is returned when the cartridge type is not emulated.
+var ErrUnsupportedType = errors.New("unsupported cartridge type")
+
+// romOnlySize is the size of the ROM of cartridges without MBC.
+const romOnlySize = 0x8000
+
+// Cartridge is a game cartridge.
+type Cartridge struct {
+	Header Header
+	ROM    []uint8
+	// Mismatch is set when the size or the global checksum of the ROM
+	// don't match the header. The hardware doesn't check them, so the
+	// game still runs, but the ROM may be patched or a bad dump.
+	Mismatch error
+
+	// Rumble, when set, is called when the rumble motor
+	// of the cartridge is turned on or off.
+	Rumble func(on bool)
+}
+
+// New creates a cartridge from the content of its ROM, returning an error
+// when the header is invalid or, like the boot ROM, its checksum is wrong.
+func New(rom []uint8) (*Cartridge, error) {
+	h, err := ParseHeader(rom)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.verifyHeaderChecksum(rom); err != nil {
+		return nil, err
+	}
+	return &Cartridge{
+		Header:   h,
+		ROM:      rom,
+		Mismatch: h.Verify(rom),
+	}, nil
+}
+
+// Load loads a cartridge from a .gb or .gbc file.
+func Load(path string) (*Cartridge, error) {
+	rom, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := New(rom)
+	if err != nil {
+		return nil, fmt.Errorf("loading %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// MBC returns the address space mapping the cartridge ROM and RAM
+// through the memory bank controller in the cartridge.
+func (c *Cartridge) MBC() (memory.AddressSpace, error) {
+	switch c.Header.Type {
+	case ROMOnly:
+		// Overdumps must not map more than 0x0000-0x7FFF.
+		rom := c.ROM
+		if len(rom) > romOnlySize {
+			rom = rom[:romOnlySize]
+		}
+		return memory.NewROM(rom, 0), nil
+	case MBC1, MBC1RAM, MBC1RAMBattery:
+		return memory.NewMBC1(c.ROM, c.Header.RAMSize), nil
+	case MBC3, MBC3RAM, MBC3RAMBattery:
+		return memory.NewMBC3(c.ROM, c.Header.RAMSize, nil), nil
+	case MBC3TimerBattery, MBC3TimerRAMBattery:
+		return memory.NewMBC3(c.ROM, c.Header.RAMSize, memory.NewRTC(time.Now)), nil
+	case MBC2, MBC2Battery:
+		return memory.NewMBC2(c.ROM), nil
+	case MBC5, MBC5RAM, MBC5RAMBattery:
+		return memory.NewMBC5(c.ROM, c.Header.RAMSize, nil), nil
+	case MBC5Rumble, MBC5RumbleRAM, MBC5RumbleRAMBattery:
+		return memory.NewMBC5(c.ROM, c.Header.RAMSize, c.rumble), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, c.Header.Type)
+}
+
+func (c *Cartridge) rumble(on bool) {
+	if c.Rumble != nil {
+		c.Rumble(on)
+	}
+}
@@ -0,0 +1,117 @@
+package cartridge_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andreaperizzato/gameboy/cartridge"
+	"github.com/andreaperizzato/gameboy/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cartridge")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "game.gb")
+	rom := newROM(cartridge.MBC1, 0x00)
+	assert.NoError(t, ioutil.WriteFile(path, rom, 0644))
+
+	c, err := cartridge.Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "TEST GAME", c.Header.Title)
+	assert.Equal(t, rom, c.ROM)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cartridge")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "game.gb")
+	rom := newROM(cartridge.MBC1, 0x00)
+	rom[0x014D]++
+	assert.NoError(t, ioutil.WriteFile(path, rom, 0644))
+
+	_, err = cartridge.Load(path)
+	assert.True(t, errors.Is(err, cartridge.ErrHeaderChecksum))
+	assert.Contains(t, err.Error(), path)
+
+	_, err = cartridge.Load(filepath.Join(dir, "missing.gb"))
+	assert.Error(t, err)
+}
+
+func TestNew_Mismatch(t *testing.T) {
+	rom := newROM(cartridge.ROMOnly, 0x00)
+	c, err := cartridge.New(rom)
+	assert.NoError(t, err)
+	assert.NoError(t, c.Mismatch)
+
+	// Patched ROMs rarely fix the global checksum, but they still run.
+	rom[0x1000]++
+	c, err = cartridge.New(rom)
+	assert.NoError(t, err)
+	assert.True(t, errors.Is(c.Mismatch, cartridge.ErrGlobalChecksum), "global checksum")
+
+	// Overdumps have more data than the header says.
+	rom = append(newROM(cartridge.ROMOnly, 0x00), make([]uint8, 0x8000)...)
+	c, err = cartridge.New(rom)
+	assert.NoError(t, err)
+	assert.True(t, errors.Is(c.Mismatch, cartridge.ErrSizeMismatch), "size")
+	mbc, err := c.MBC()
+	assert.NoError(t, err)
+	assert.True(t, mbc.Contains(0x7FFF), "ROM")
+	assert.False(t, mbc.Contains(0x8000), "VRAM")
+}
+
+func TestCartridge_MBC(t *testing.T) {
+	tests := []struct {
+		typ cartridge.Type
+		exp interface{}
+	}{
+		{cartridge.ROMOnly, &memory.ROM{}},
+		{cartridge.MBC1, &memory.MBC1{}},
+		{cartridge.MBC1RAMBattery, &memory.MBC1{}},
+		{cartridge.MBC3, &memory.MBC3{}},
+		{cartridge.MBC3TimerRAMBattery, &memory.MBC3{}},
+		{cartridge.MBC2Battery, &memory.MBC2{}},
+		{cartridge.MBC5RAM, &memory.MBC5{}},
+		{cartridge.MBC5RumbleRAMBattery, &memory.MBC5{}},
+	}
+	for _, tC := range tests {
+		t.Run(tC.typ.String(), func(t *testing.T) {
+			c, err := cartridge.New(newROM(tC.typ, 0x00))
+			assert.NoError(t, err)
+			mbc, err := c.MBC()
+			assert.NoError(t, err)
+			assert.IsType(t, tC.exp, mbc)
+		})
+	}
+}
+
+func TestCartridge_MBCUnsupported(t *testing.T) {
+	c, err := cartridge.New(newROM(cartridge.HuC3, 0x00))
+	assert.NoError(t, err)
+	_, err = c.MBC()
+	assert.True(t, errors.Is(err, cartridge.ErrUnsupportedType))
+	assert.Contains(t, err.Error(), "HuC3")
+}
+
+func TestCartridge_Rumble(t *testing.T) {
+	c, err := cartridge.New(newROM(cartridge.MBC5Rumble, 0x00))
+	assert.NoError(t, err)
+	mbc, err := c.MBC()
+	assert.NoError(t, err)
+
+	// Setting the callback after creating the MBC works too.
+	on := false
+	c.Rumble = func(v bool) { on = v }
+	mbc.Write(0x4000, 0x08)
+	assert.True(t, on, "motor on")
+	mbc.Write(0x4000, 0x00)
+	assert.False(t, on, "motor off")
+}
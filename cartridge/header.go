@@ -0,0 +1,210 @@
+package cartridge
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// More info about the cartridge header can be found here:
+// https://gbdev.io/pandocs/#the-cartridge-header
+
+const (
+	titleAddr           = 0x0134
+	manufacturerAddr    = 0x013F
+	cgbFlagAddr         = 0x0143
+	newLicenseeAddr     = 0x0144
+	sgbFlagAddr         = 0x0146
+	typeAddr            = 0x0147
+	romSizeAddr         = 0x0148
+	ramSizeAddr         = 0x0149
+	destinationAddr     = 0x014A
+	oldLicenseeAddr     = 0x014B
+	versionAddr         = 0x014C
+	headerChecksumAddr  = 0x014D
+	globalChecksumAddr  = 0x014E
+	headerEnd           = 0x0150
+	useNewLicenseeCode  = 0x33
+	sgbSupported        = 0x03
+	cgbSupported        = 0x80
+	cgbOnly             = 0xC0
+	romBankSize         = 0x4000
+	minROMSize          = 2 * romBankSize
+	maxROMSizeCode      = 0x08
+	titleLength         = 16
+	manufacturerLength  = 4
+	cgbTitleLength      = 11
+	cgbFlagMask         = 0x80
+	newLicenseeCodeSize = 2
+)
+
+// Errors returned when parsing or verifying a cartridge.
+var (
+	ErrTooSmall       = errors.New("rom is too small to contain a header")
+	ErrUnknownROMSize = errors.New("unknown rom size")
+	ErrUnknownRAMSize = errors.New("unknown ram size")
+	ErrSizeMismatch   = errors.New("rom size does not match the header")
+	ErrHeaderChecksum = errors.New("invalid header checksum")
+	ErrGlobalChecksum = errors.New("invalid global checksum")
+)
+
+// ramSizes maps the RAM size code to the size in bytes.
+// 0x01 is unofficial and was never used by licensed cartridges.
+var ramSizes = map[uint8]int{
+	0x00: 0,
+	0x01: 2 * 1024,
+	0x02: 8 * 1024,
+	0x03: 32 * 1024,
+	0x04: 128 * 1024,
+	0x05: 64 * 1024,
+}
+
+// Header is the cartridge header found at 0x0100-0x014F.
+type Header struct {
+	// Title is the upper case title of the game.
+	Title string
+	// ManufacturerCode is only present in newer cartridges
+	// which use the last bytes of the title for it.
+	ManufacturerCode string
+	// CGBFlag tells whether the game supports or requires the Gameboy Color.
+	CGBFlag uint8
+	// SGBFlag tells whether the game supports the Super Gameboy.
+	SGBFlag uint8
+	// Type is the memory bank controller and the hardware in the cartridge.
+	Type Type
+	// ROMSize is the size of the ROM in bytes.
+	ROMSize int
+	// RAMSize is the size of the external RAM in bytes.
+	RAMSize int
+	// Japanese is true when the game is sold in Japan.
+	Japanese bool
+	// Licensee is the publisher code: when OldLicensee is 0x33,
+	// it's the two-character new code, otherwise the old one in hex.
+	Licensee    string
+	OldLicensee uint8
+	// Version is the version number of the game, usually 0.
+	Version uint8
+	// HeaderChecksum is the checksum of bytes 0x0134-0x014C.
+	HeaderChecksum uint8
+	// GlobalChecksum is the checksum of the whole ROM, excluding itself.
+	GlobalChecksum uint16
+}
+
+// ParseHeader parses the header of a ROM.
+// It doesn't verify the checksums, use Verify for that.
+func ParseHeader(rom []uint8) (Header, error) {
+	if len(rom) < headerEnd {
+		return Header{}, fmt.Errorf("%w: got %d bytes", ErrTooSmall, len(rom))
+	}
+	romSizeCode := rom[romSizeAddr]
+	if romSizeCode > maxROMSizeCode {
+		return Header{}, fmt.Errorf("%w: code 0x%02X", ErrUnknownROMSize, romSizeCode)
+	}
+	ramSize, ok := ramSizes[rom[ramSizeAddr]]
+	if !ok {
+		return Header{}, fmt.Errorf("%w: code 0x%02X", ErrUnknownRAMSize, rom[ramSizeAddr])
+	}
+
+	h := Header{
+		CGBFlag:        rom[cgbFlagAddr],
+		SGBFlag:        rom[sgbFlagAddr],
+		Type:           Type(rom[typeAddr]),
+		ROMSize:        minROMSize << romSizeCode,
+		RAMSize:        ramSize,
+		Japanese:       rom[destinationAddr] == 0x00,
+		OldLicensee:    rom[oldLicenseeAddr],
+		Version:        rom[versionAddr],
+		HeaderChecksum: rom[headerChecksumAddr],
+		GlobalChecksum: uint16(rom[globalChecksumAddr])<<8 | uint16(rom[globalChecksumAddr+1]),
+	}
+
+	// On CGB cartridges the title is shorter, the CGB flag takes its
+	// last byte and newer ones also have a manufacturer code before that.
+	title := rom[titleAddr : titleAddr+titleLength]
+	if h.CGBFlag&cgbFlagMask != 0 {
+		title = rom[titleAddr : titleAddr+titleLength-1]
+		code := rom[manufacturerAddr : manufacturerAddr+manufacturerLength]
+		if isUpperASCII(code) {
+			h.ManufacturerCode = string(code)
+			title = rom[titleAddr : titleAddr+cgbTitleLength]
+		}
+	}
+	h.Title = strings.TrimRight(string(title), "\x00 ")
+
+	if h.OldLicensee == useNewLicenseeCode {
+		h.Licensee = string(rom[newLicenseeAddr : newLicenseeAddr+newLicenseeCodeSize])
+	} else {
+		h.Licensee = fmt.Sprintf("%02X", h.OldLicensee)
+	}
+	return h, nil
+}
+
+// Verify checks that the ROM matches the size and the checksums in the header.
+func (h Header) Verify(rom []uint8) error {
+	if len(rom) != h.ROMSize {
+		return fmt.Errorf("%w: header says %d bytes, got %d", ErrSizeMismatch, h.ROMSize, len(rom))
+	}
+	if err := h.verifyHeaderChecksum(rom); err != nil {
+		return err
+	}
+	if sum := globalChecksum(rom); sum != h.GlobalChecksum {
+		return fmt.Errorf("%w: header says 0x%04X, computed 0x%04X", ErrGlobalChecksum, h.GlobalChecksum, sum)
+	}
+	return nil
+}
+
+// verifyHeaderChecksum checks the header checksum, which is the only
+// one the boot ROM verifies before starting the game.
+func (h Header) verifyHeaderChecksum(rom []uint8) error {
+	if sum := headerChecksum(rom); sum != h.HeaderChecksum {
+		return fmt.Errorf("%w: header says 0x%02X, computed 0x%02X", ErrHeaderChecksum, h.HeaderChecksum, sum)
+	}
+	return nil
+}
+
+// CGBSupported returns true when the game has Gameboy Color enhancements.
+func (h Header) CGBSupported() bool {
+	return h.CGBFlag == cgbSupported || h.CGBFlag == cgbOnly
+}
+
+// CGBOnly returns true when the game only works on the Gameboy Color.
+func (h Header) CGBOnly() bool {
+	return h.CGBFlag == cgbOnly
+}
+
+// SGBSupported returns true when the game has Super Gameboy enhancements.
+func (h Header) SGBSupported() bool {
+	return h.SGBFlag == sgbSupported
+}
+
+// headerChecksum computes the checksum of bytes 0x0134-0x014C,
+// which is verified by the boot ROM.
+func headerChecksum(rom []uint8) uint8 {
+	sum := uint8(0)
+	for _, b := range rom[titleAddr:headerChecksumAddr] {
+		sum = sum - b - 1
+	}
+	return sum
+}
+
+// globalChecksum computes the sum of all bytes in the ROM
+// except the two holding the global checksum.
+func globalChecksum(rom []uint8) uint16 {
+	sum := uint16(0)
+	for i, b := range rom {
+		if i == globalChecksumAddr || i == globalChecksumAddr+1 {
+			continue
+		}
+		sum += uint16(b)
+	}
+	return sum
+}
+
+func isUpperASCII(b []uint8) bool {
+	for _, c := range b {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
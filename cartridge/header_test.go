@@ -0,0 +1,128 @@
+package cartridge_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/andreaperizzato/gameboy/cartridge"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHeader(t *testing.T) {
+	rom := newROM(cartridge.MBC1RAMBattery, 0x03)
+	h, err := cartridge.ParseHeader(rom)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "TEST GAME", h.Title)
+	assert.Equal(t, "", h.ManufacturerCode)
+	assert.Equal(t, cartridge.MBC1RAMBattery, h.Type)
+	assert.Equal(t, 64*1024, h.ROMSize)
+	assert.Equal(t, 32*1024, h.RAMSize)
+	assert.Equal(t, "01", h.Licensee)
+	assert.Equal(t, uint8(0x02), h.Version)
+	assert.True(t, h.Japanese)
+	assert.False(t, h.CGBSupported())
+	assert.True(t, h.SGBSupported())
+	assert.NoError(t, h.Verify(rom))
+}
+
+func TestParseHeader_CGB(t *testing.T) {
+	rom := newROM(cartridge.ROMOnly, 0x00)
+	copy(rom[0x0134:], "ABCDEFGHIJKAXYZ\xC0")
+	rom[0x014B] = 0x33
+	copy(rom[0x0144:], "8P")
+	h, err := cartridge.ParseHeader(rom)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ABCDEFGHIJK", h.Title)
+	assert.Equal(t, "AXYZ", h.ManufacturerCode)
+	assert.Equal(t, "8P", h.Licensee)
+	assert.True(t, h.CGBSupported())
+	assert.True(t, h.CGBOnly())
+}
+
+func TestParseHeader_Errors(t *testing.T) {
+	_, err := cartridge.ParseHeader(make([]uint8, 0x0100))
+	assert.True(t, errors.Is(err, cartridge.ErrTooSmall), "too small")
+
+	rom := newROM(cartridge.ROMOnly, 0x00)
+	rom[0x0148] = 0x52
+	_, err = cartridge.ParseHeader(rom)
+	assert.True(t, errors.Is(err, cartridge.ErrUnknownROMSize), "rom size")
+
+	rom = newROM(cartridge.ROMOnly, 0x00)
+	rom[0x0149] = 0x06
+	_, err = cartridge.ParseHeader(rom)
+	assert.True(t, errors.Is(err, cartridge.ErrUnknownRAMSize), "ram size")
+}
+
+func TestHeader_Verify(t *testing.T) {
+	rom := newROM(cartridge.ROMOnly, 0x00)
+	h, _ := cartridge.ParseHeader(rom)
+	assert.NoError(t, h.Verify(rom))
+
+	err := h.Verify(rom[:0x4000])
+	assert.True(t, errors.Is(err, cartridge.ErrSizeMismatch), "size")
+
+	rom[0x0134]++
+	err = h.Verify(rom)
+	assert.True(t, errors.Is(err, cartridge.ErrHeaderChecksum), "header checksum")
+	assert.Contains(t, err.Error(), "header says 0x")
+
+	rom = newROM(cartridge.ROMOnly, 0x00)
+	rom[0x2000]++
+	err = h.Verify(rom)
+	assert.True(t, errors.Is(err, cartridge.ErrGlobalChecksum), "global checksum")
+}
+
+func TestType(t *testing.T) {
+	assert.Equal(t, "MBC3+TIMER+RAM+BATTERY", cartridge.MBC3TimerRAMBattery.String())
+	assert.Equal(t, "UNKNOWN (0x42)", cartridge.Type(0x42).String())
+	assert.True(t, cartridge.MBC3TimerRAMBattery.HasBattery())
+	assert.True(t, cartridge.MBC3TimerRAMBattery.HasTimer())
+	assert.False(t, cartridge.MBC1RAM.HasBattery())
+	assert.True(t, cartridge.MBC5Rumble.HasRumble())
+}
+
+// newROM returns a ROM of the given type and size
+// with a valid header and checksums.
+func newROM(typ cartridge.Type, ramSizeCode uint8) []uint8 {
+	romSizeCode := uint8(0x00)
+	if typ != cartridge.ROMOnly {
+		romSizeCode = 0x01
+	}
+	rom := make([]uint8, 0x8000<<romSizeCode)
+	// Fill the banks with data to make checksums meaningful.
+	for i := range rom {
+		rom[i] = uint8(i / 0x4000)
+	}
+	copy(rom[0x0134:0x0144], make([]uint8, 16))
+	copy(rom[0x0134:], "TEST GAME")
+	rom[0x0143] = 0x00
+	rom[0x0146] = 0x03
+	rom[0x0147] = uint8(typ)
+	rom[0x0148] = romSizeCode
+	rom[0x0149] = ramSizeCode
+	rom[0x014A] = 0x00
+	rom[0x014B] = 0x01
+	rom[0x014C] = 0x02
+	fixChecksums(rom)
+	return rom
+}
+
+func fixChecksums(rom []uint8) {
+	sum := uint8(0)
+	for _, b := range rom[0x0134:0x014D] {
+		sum = sum - b - 1
+	}
+	rom[0x014D] = sum
+
+	global := uint16(0)
+	for i, b := range rom {
+		if i != 0x014E && i != 0x014F {
+			global += uint16(b)
+		}
+	}
+	rom[0x014E] = uint8(global >> 8)
+	rom[0x014F] = uint8(global)
+}
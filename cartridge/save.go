@@ -0,0 +1,89 @@
+package cartridge
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andreaperizzato/gameboy/memory"
+)
+
+// SavePath returns the path of the save file for a ROM,
+// which has the same name with the .sav extension.
+func SavePath(romPath string) string {
+	return strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".sav"
+}
+
+// SaveFile keeps the battery-backed memory of a cartridge in a file.
+type SaveFile struct {
+	path string
+	mem  memory.BatteryBacked
+	// last is the content of the file, so
+	// that unchanged memory is not written again.
+	last []uint8
+}
+
+// NewSaveFile creates a new save file at the given path.
+func NewSaveFile(path string, mem memory.BatteryBacked) *SaveFile {
+	return &SaveFile{
+		path: path,
+		mem:  mem,
+	}
+}
+
+// Load loads the content of the file into memory.
+// It's not an error if the file doesn't exist yet.
+func (f *SaveFile) Load() error {
+	data, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := f.mem.Load(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("loading %s: %w", f.path, err)
+	}
+	f.last = data
+	return nil
+}
+
+// Flush writes the memory to the file if it changed since the last time.
+// The content is written to a temporary file first and then renamed,
+// so that a crash can't leave a corrupted save behind.
+func (f *SaveFile) Flush() error {
+	var buf bytes.Buffer
+	if err := f.mem.Save(&buf); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	if f.last != nil && bytes.Equal(data, f.last) {
+		return nil
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), filepath.Base(f.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	// Removing fails once the file has been renamed, which is fine.
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return err
+	}
+	f.last = data
+	return nil
+}
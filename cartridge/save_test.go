@@ -0,0 +1,79 @@
+package cartridge_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andreaperizzato/gameboy/cartridge"
+	"github.com/andreaperizzato/gameboy/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSavePath(t *testing.T) {
+	assert.Equal(t, "roms/game.sav", cartridge.SavePath("roms/game.gb"))
+	assert.Equal(t, "roms/game.sav", cartridge.SavePath("roms/game.gbc"))
+	assert.Equal(t, "game.sav", cartridge.SavePath("game"))
+}
+
+func TestSaveFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "save")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "game.sav")
+
+	mbc := memory.NewMBC1(make([]uint8, 0x8000), 8*1024)
+	save := cartridge.NewSaveFile(path, mbc)
+	// A missing file is not an error.
+	assert.NoError(t, save.Load())
+
+	mbc.Write(0x0000, 0x0A)
+	mbc.Write(0xA001, 0xAB)
+	assert.NoError(t, save.Flush())
+
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Len(t, data, 8*1024)
+	assert.Equal(t, uint8(0xAB), data[1])
+
+	// Only the save is left in the directory.
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	loaded := memory.NewMBC1(make([]uint8, 0x8000), 8*1024)
+	assert.NoError(t, cartridge.NewSaveFile(path, loaded).Load())
+	loaded.Write(0x0000, 0x0A)
+	assert.Equal(t, uint8(0xAB), loaded.Read(0xA001))
+}
+
+func TestSaveFile_Unchanged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "save")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "game.sav")
+
+	mbc := memory.NewMBC5(make([]uint8, 0x8000), 8*1024, nil)
+	save := cartridge.NewSaveFile(path, mbc)
+	assert.NoError(t, save.Flush())
+
+	// Unchanged memory is not written again.
+	assert.NoError(t, os.Remove(path))
+	assert.NoError(t, save.Flush())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSaveFile_TooSmall(t *testing.T) {
+	dir, err := ioutil.TempDir("", "save")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "game.sav")
+	assert.NoError(t, ioutil.WriteFile(path, make([]uint8, 10), 0644))
+
+	mbc := memory.NewMBC2(make([]uint8, 0x8000))
+	err = cartridge.NewSaveFile(path, mbc).Load()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), path)
+}
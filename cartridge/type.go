@@ -0,0 +1,106 @@
+package cartridge
+
+import "fmt"
+
+// Type is the cartridge type stored in the header,
+// telling which memory bank controller and hardware are in the cartridge.
+// https://gbdev.io/pandocs/#_0147-cartridge-type
+type Type uint8
+
+// Known cartridge types.
+const (
+	ROMOnly                    Type = 0x00
+	MBC1                       Type = 0x01
+	MBC1RAM                    Type = 0x02
+	MBC1RAMBattery             Type = 0x03
+	MBC2                       Type = 0x05
+	MBC2Battery                Type = 0x06
+	ROMRAM                     Type = 0x08
+	ROMRAMBattery              Type = 0x09
+	MMM01                      Type = 0x0B
+	MMM01RAM                   Type = 0x0C
+	MMM01RAMBattery            Type = 0x0D
+	MBC3TimerBattery           Type = 0x0F
+	MBC3TimerRAMBattery        Type = 0x10
+	MBC3                       Type = 0x11
+	MBC3RAM                    Type = 0x12
+	MBC3RAMBattery             Type = 0x13
+	MBC5                       Type = 0x19
+	MBC5RAM                    Type = 0x1A
+	MBC5RAMBattery             Type = 0x1B
+	MBC5Rumble                 Type = 0x1C
+	MBC5RumbleRAM              Type = 0x1D
+	MBC5RumbleRAMBattery       Type = 0x1E
+	MBC6                       Type = 0x20
+	MBC7SensorRumbleRAMBattery Type = 0x22
+	PocketCamera               Type = 0xFC
+	BandaiTAMA5                Type = 0xFD
+	HuC3                       Type = 0xFE
+	HuC1RAMBattery             Type = 0xFF
+)
+
+var typeNames = map[Type]string{
+	ROMOnly:                    "ROM ONLY",
+	MBC1:                       "MBC1",
+	MBC1RAM:                    "MBC1+RAM",
+	MBC1RAMBattery:             "MBC1+RAM+BATTERY",
+	MBC2:                       "MBC2",
+	MBC2Battery:                "MBC2+BATTERY",
+	ROMRAM:                     "ROM+RAM",
+	ROMRAMBattery:              "ROM+RAM+BATTERY",
+	MMM01:                      "MMM01",
+	MMM01RAM:                   "MMM01+RAM",
+	MMM01RAMBattery:            "MMM01+RAM+BATTERY",
+	MBC3TimerBattery:           "MBC3+TIMER+BATTERY",
+	MBC3TimerRAMBattery:        "MBC3+TIMER+RAM+BATTERY",
+	MBC3:                       "MBC3",
+	MBC3RAM:                    "MBC3+RAM",
+	MBC3RAMBattery:             "MBC3+RAM+BATTERY",
+	MBC5:                       "MBC5",
+	MBC5RAM:                    "MBC5+RAM",
+	MBC5RAMBattery:             "MBC5+RAM+BATTERY",
+	MBC5Rumble:                 "MBC5+RUMBLE",
+	MBC5RumbleRAM:              "MBC5+RUMBLE+RAM",
+	MBC5RumbleRAMBattery:       "MBC5+RUMBLE+RAM+BATTERY",
+	MBC6:                       "MBC6",
+	MBC7SensorRumbleRAMBattery: "MBC7+SENSOR+RUMBLE+RAM+BATTERY",
+	PocketCamera:               "POCKET CAMERA",
+	BandaiTAMA5:                "BANDAI TAMA5",
+	HuC3:                       "HuC3",
+	HuC1RAMBattery:             "HuC1+RAM+BATTERY",
+}
+
+// String returns the name of the type as listed in the pandocs.
+func (t Type) String() string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("UNKNOWN (0x%02X)", uint8(t))
+}
+
+// HasBattery returns true when the cartridge keeps its RAM
+// (or clock) powered by a battery and must be saved.
+func (t Type) HasBattery() bool {
+	switch t {
+	case MBC1RAMBattery, MBC2Battery, ROMRAMBattery, MMM01RAMBattery,
+		MBC3TimerBattery, MBC3TimerRAMBattery, MBC3RAMBattery,
+		MBC5RAMBattery, MBC5RumbleRAMBattery, MBC7SensorRumbleRAMBattery,
+		HuC1RAMBattery:
+		return true
+	}
+	return false
+}
+
+// HasTimer returns true when the cartridge has a real-time clock.
+func (t Type) HasTimer() bool {
+	return t == MBC3TimerBattery || t == MBC3TimerRAMBattery
+}
+
+// HasRumble returns true when the cartridge has a rumble motor.
+func (t Type) HasRumble() bool {
+	switch t {
+	case MBC5Rumble, MBC5RumbleRAM, MBC5RumbleRAMBattery, MBC7SensorRumbleRAMBattery:
+		return true
+	}
+	return false
+}
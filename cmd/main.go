@@ -1,11 +1,18 @@
 package main
 
 import (
+	"flag"
+	"log"
+
 	"github.com/andreaperizzato/gameboy/apu"
+	"github.com/andreaperizzato/gameboy/cartridge"
 	"github.com/andreaperizzato/gameboy/cpu"
+	"github.com/andreaperizzato/gameboy/interrupts"
 	"github.com/andreaperizzato/gameboy/memory"
+	"github.com/andreaperizzato/gameboy/model"
 	"github.com/andreaperizzato/gameboy/ppu"
 	"github.com/andreaperizzato/gameboy/screen"
+	"github.com/andreaperizzato/gameboy/timer"
 )
 
 // https://gbdev.gg8.se/wiki/articles/Gameboy_Bootstrap_ROM
@@ -24,28 +31,159 @@ var logo = []uint8{
 	0xDD, 0xDC, 0x99, 0x9F, 0xBB, 0xB9, 0x33, 0x3E,
 }
 
+const (
+	// frameCycles is the number of cycles in a frame.
+	frameCycles = 70224
+	// saveInterval is the number of frames, about 5 seconds,
+	// between two flushes of the battery-backed memory.
+	saveInterval = 300
+)
+
+var (
+	modelName = flag.String("model", "dmg", "hardware model: dmg0, dmg, mgb, sgb, cgb or agb")
+	bootPath  = flag.String("boot", "", "boot ROM file of the model, the DMG one is built in")
+	skipBoot  = flag.Bool("skipboot", false, "start from the cartridge as if the boot ROM had run")
+)
+
 func main() {
-	ram := memory.NewRAM(0xFFFF, 0)
-	// Just write the logo so it shows up.
-	for i, b := range logo {
-		ram.Write(0x0104+uint16(i), b)
+	flag.Parse()
+	hw, err := model.Parse(*modelName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	boot := memory.NewDMGBootROM()
+	if *bootPath != "" {
+		boot, err = memory.LoadBootROM(hw, *bootPath)
+		if err != nil {
+			log.Fatalf("Failed to load boot ROM: %v", err)
+		}
+	} else if hw != model.DMG && !*skipBoot {
+		log.Fatalf("The %s boot ROM is not built in, use -boot or -skipboot", hw)
 	}
 
-	mmu := memory.NewMMU(memory.NewGBCBootROM(), ram)
-	cpux := cpu.NewGBC(mmu)
+	irq := interrupts.New()
+	tmr := timer.New(irq)
+	spaces := []memory.AddressSpace{irq, tmr}
+	// Without a cartridge, use an empty ROM with just the logo so it shows up.
+	rom := make([]uint8, 0x8000)
+	copy(rom[0x0104:], logo)
+	var cartSpace memory.AddressSpace = memory.NewROM(rom, 0)
+	// rtc is the cartridge real-time clock, which needs to be clocked.
+	var rtc *memory.MBC3
+	// save keeps battery-backed memory in a file, it's nil when there is none.
+	var save *cartridge.SaveFile
+	// headerChecksum affects the flags left by the boot ROM.
+	var headerChecksum uint8
+	if flag.NArg() > 0 {
+		cart, err := cartridge.Load(flag.Arg(0))
+		if err != nil {
+			log.Fatalf("Failed to load cartridge: %v", err)
+		}
+		log.Printf("Loaded %s (%s)", cart.Header.Title, cart.Header.Type)
+		if cart.Mismatch != nil {
+			log.Printf("Warning: %v", cart.Mismatch)
+		}
+		mbc, err := cart.MBC()
+		if err != nil {
+			log.Fatalf("Failed to load cartridge: %v", err)
+		}
+		cartSpace = mbc
+		headerChecksum = cart.Header.HeaderChecksum
+		if m, ok := mbc.(*memory.MBC3); ok && cart.Header.Type.HasTimer() {
+			rtc = m
+		}
+		if b, ok := mbc.(memory.BatteryBacked); ok && cart.Header.Type.HasBattery() {
+			save = cartridge.NewSaveFile(cartridge.SavePath(flag.Arg(0)), b)
+			if err := save.Load(); err != nil {
+				log.Fatalf("Failed to load save: %v", err)
+			}
+		}
+	}
+	memmap := memory.NewDMGMap(hw, cartSpace)
+	spaces = append(spaces, memmap.Spaces()...)
+	mmu := memory.NewMMU(boot, spaces...)
+	// The CPU goes through the DMA, which restricts its access during transfers.
+	dma := memory.NewDMA(mmu, memmap.IO)
+	cpux := cpu.New(hw, dma, irq)
+	if *skipBoot {
+		cpux.SkipBoot(hw, headerChecksum)
+		tmr.SkipBoot(hw)
+		memmap.IO.SkipBoot(hw)
+		// The boot ROM ends with a VBlank interrupt requested
+		// and disables itself by writing 0xFF50.
+		irq.Request(interrupts.VBlank)
+		mmu.Write(0xFF50, 0x01)
+	}
 	scrx := screen.New()
-	ppux := ppu.New(mmu, scrx)
-	apux := apu.NewAPU(mmu)
+	ppux := ppu.New(hw, mmu, memmap.IO, irq, scrx)
+	memmap.Unusable.OAMBlocked = ppux.OAMBlocked
+	apux := apu.NewAPU(memmap.IO)
 
+	quit := make(chan struct{})
+	done := make(chan struct{})
 	go func() {
-		for {
+		defer close(done)
+		for cycles, frames := 0, 0; ; cycles++ {
 			cpux.Tick()
+			dma.Tick()
+			tmr.Tick()
+			if cpux.DoubleSpeed() {
+				// The CPU, OAM DMA and the timer run twice as fast.
+				cpux.Tick()
+				dma.Tick()
+				tmr.Tick()
+			}
+			if rtc != nil {
+				rtc.Tick()
+			}
+			// The PPU draws a dot per clock cycle, like the other
+			// components it's ticked once per iteration.
 			ppux.Tick()
-			ppux.Tick()
-			ppux.Tick()
-			ppux.Tick()
+
+			if cycles < frameCycles {
+				continue
+			}
+			cycles = 0
+			if err := cpux.Err(); err != nil {
+				report(err)
+				return
+			}
+			select {
+			case <-quit:
+				return
+			default:
+			}
+			frames++
+			if frames == saveInterval {
+				frames = 0
+				flush(save)
+			}
 		}
 	}()
 	apux.Start()
 	scrx.Start()
+
+	// The window has been closed: stop the emulation and save.
+	close(quit)
+	<-done
+	flush(save)
+}
+
+// report logs why the CPU stopped, with the last instructions it executed.
+func report(err error) {
+	log.Printf("Emulation stopped: %v", err)
+	if l, ok := err.(*cpu.LockupError); ok {
+		for _, t := range l.History {
+			log.Printf("  %s", t)
+		}
+	}
+}
+
+func flush(save *cartridge.SaveFile) {
+	if save == nil {
+		return
+	}
+	if err := save.Flush(); err != nil {
+		log.Printf("Failed to save: %v", err)
+	}
 }
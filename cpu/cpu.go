@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/andreaperizzato/gameboy/interrupts"
 	"github.com/andreaperizzato/gameboy/memory"
+	"github.com/andreaperizzato/gameboy/model"
 )
 
 type registers struct {
@@ -26,6 +28,41 @@ type flags struct {
 	C bool
 }
 
+// Bits of the flags in the F register.
+const (
+	flagBitZ = uint8(1 << 7)
+	flagBitN = uint8(1 << 6)
+	flagBitH = uint8(1 << 5)
+	flagBitC = uint8(1 << 4)
+)
+
+// byte returns the flags as the F register.
+// The lowest 4 bits of F are always zero.
+func (f flags) byte() uint8 {
+	v := uint8(0)
+	if f.Z {
+		v |= flagBitZ
+	}
+	if f.N {
+		v |= flagBitN
+	}
+	if f.H {
+		v |= flagBitH
+	}
+	if f.C {
+		v |= flagBitC
+	}
+	return v
+}
+
+// setByte sets the flags from the value of the F register.
+func (f *flags) setByte(v uint8) {
+	f.Z = v&flagBitZ != 0
+	f.N = v&flagBitN != 0
+	f.H = v&flagBitH != 0
+	f.C = v&flagBitC != 0
+}
+
 type runnable func(c *CPU) uint8
 
 type instruction struct {
@@ -34,41 +71,249 @@ type instruction struct {
 	run runnable
 }
 
+// historySize is the number of executed instructions kept for error reports.
+const historySize = 16
+
+// Trace is an executed instruction.
+type Trace struct {
+	PC uint16
+	// Opcode is the opcode, 0xCBxx for the extended instruction set.
+	Opcode uint16
+	Name   string
+}
+
+func (t Trace) String() string {
+	return fmt.Sprintf("0x%04X: 0x%04X %s", t.PC, t.Opcode, t.Name)
+}
+
+// LockupError is reported when the CPU executes an illegal opcode.
+// Like the real hardware, the CPU then stops until it is reset.
+type LockupError struct {
+	Opcode uint8
+	PC     uint16
+	// History holds the last instructions executed before, the oldest first.
+	History []Trace
+}
+
+func (e *LockupError) Error() string {
+	return fmt.Sprintf("cpu locked up by illegal opcode 0x%02X at 0x%04X", e.Opcode, e.PC)
+}
+
 // CPU emulates a CPU.
 type CPU struct {
 	mem   memory.AddressSpace
 	regs  registers
 	flags flags
-	instr map[uint16]instruction
 	wait  uint8
 	b     strings.Builder
+
+	irq *interrupts.Controller
+	// ime is the Interrupt Master Enable flag.
+	ime bool
+	// eiDelay counts the instructions to execute before EI sets IME.
+	eiDelay uint8
+	// halted is true after HALT until an interrupt is pending.
+	halted bool
+	// haltBug is true when the next opcode fetch must not increment PC.
+	haltBug bool
+	// stopped is true after STOP until a button is pressed.
+	stopped bool
+
+	// history holds the last executed instructions, next is the index of
+	// the oldest one (or of the first free one while it's not full).
+	history     [historySize]Trace
+	historyNext int
+	historyFull bool
+	// err is set when the CPU locks up.
+	err *LockupError
+
+	// model is the hardware model, the Gameboy Color supports double speed.
+	model       model.Model
+	doubleSpeed bool
+	// pause counts the cycles left in a speed switch, the CPU doesn't run meanwhile.
+	pause int
 }
 
-// NewGBC creats a new CPU with the GBC instruction set.
-func NewGBC(mmu memory.AddressSpace) *CPU {
+const (
+	divAddr = uint16(0xFF04)
+	// KEY1 - Prepare Speed Switch
+	// https://gbdev.io/pandocs/#ff4d-key1-cgb-mode-only-prepare-speed-switch
+	key1Addr = uint16(0xFF4D)
+
+	key1Armed = uint8(1 << 0)
+	key1Speed = uint8(1 << 7)
+
+	// speedSwitchCycles is the duration of a speed switch, 2050 machine cycles.
+	speedSwitchCycles = 2050 * 4
+)
+
+// New creates a new CPU of the given model with the GBC instruction set.
+func New(m model.Model, mmu memory.AddressSpace, irq *interrupts.Controller) *CPU {
 	return &CPU{
 		mem:   mmu,
-		instr: gbcInstructions,
+		irq:   irq,
+		model: m,
+	}
+}
+
+// postBoot is the state of the registers left by the boot ROM of each model.
+// https://gbdev.io/pandocs/#power-up-sequence
+var postBoot = map[model.Model]struct {
+	regs  registers
+	flags flags
+}{
+	model.DMG0: {registers{A: 0x01, B: 0xFF, C: 0x13, E: 0xC1, H: 0x84, L: 0x03, SP: 0xFFFE, PC: 0x0100}, flags{}},
+	model.DMG:  {registers{A: 0x01, C: 0x13, E: 0xD8, H: 0x01, L: 0x4D, SP: 0xFFFE, PC: 0x0100}, flags{Z: true}},
+	model.MGB:  {registers{A: 0xFF, C: 0x13, E: 0xD8, H: 0x01, L: 0x4D, SP: 0xFFFE, PC: 0x0100}, flags{Z: true}},
+	model.SGB:  {registers{A: 0x01, C: 0x14, H: 0xC0, L: 0x60, SP: 0xFFFE, PC: 0x0100}, flags{}},
+	model.CGB:  {registers{A: 0x11, D: 0xFF, E: 0x56, L: 0x0D, SP: 0xFFFE, PC: 0x0100}, flags{Z: true}},
+	model.AGB:  {registers{A: 0x11, B: 0x01, D: 0xFF, E: 0x56, L: 0x0D, SP: 0xFFFE, PC: 0x0100}, flags{}},
+}
+
+// SkipBoot sets the registers as the boot ROM of the model leaves them,
+// so that execution starts from the cartridge entry point at 0x0100.
+// On DMG and MGB, the H and C flags are set when the header checksum isn't 0.
+func (c *CPU) SkipBoot(m model.Model, headerChecksum uint8) {
+	s := postBoot[m]
+	c.regs = s.regs
+	c.flags = s.flags
+	if m == model.DMG || m == model.MGB {
+		c.flags.H = headerChecksum != 0
+		c.flags.C = headerChecksum != 0
 	}
 }
 
+// DoubleSpeed returns true when the CPU runs at double speed and must be
+// ticked twice as often as the PPU and the APU. The timer and OAM DMA
+// follow the CPU speed.
+func (c *CPU) DoubleSpeed() bool {
+	return c.doubleSpeed
+}
+
+// Err returns a *LockupError when the CPU has locked up, or nil.
+func (c *CPU) Err() error {
+	if c.err == nil {
+		return nil
+	}
+	return c.err
+}
+
 // Tick executes one CPU step.
 func (c *CPU) Tick() {
+	if c.err != nil {
+		return
+	}
+	if c.pause > 0 {
+		c.pause--
+		return
+	}
 	if c.wait > 0 {
 		c.wait--
 		return
 	}
+	if c.stopped {
+		// Pressing a button wakes up the CPU.
+		if !c.irq.Requested(interrupts.Joypad) {
+			return
+		}
+		c.stopped = false
+	}
+	if c.serviceInterrupt() {
+		return
+	}
+	if c.halted {
+		return
+	}
 
 	initialPC := c.regs.PC
 	opcode := uint16(nextArg(c))
+	if c.haltBug {
+		c.haltBug = false
+		c.regs.PC--
+	}
 	if opcode == 0xCB {
 		opcode = 0xCB00 | uint16(nextArg(c))
 	}
-	cmd, found := c.instr[opcode]
-	if !found {
-		err := fmt.Sprintf("opcode 0x%04X not found at 0x%04X", opcode, initialPC)
-		panic(err)
+	run := lookup(opcode).run
+	if run == nil {
+		// Only base opcodes can be illegal, all the CB ones exist.
+		c.err = &LockupError{
+			Opcode:  uint8(opcode),
+			PC:      initialPC,
+			History: c.traces(),
+		}
+		return
 	}
-	c.wait = cmd.run(c)
+	c.trace(initialPC, opcode)
+	c.wait = run(c)
 	c.wait--
+
+	// EI takes effect only after the instruction following it.
+	if c.eiDelay > 0 {
+		c.eiDelay--
+		if c.eiDelay == 0 {
+			c.ime = true
+		}
+	}
+}
+
+// lookup returns the instruction for the opcode,
+// which is 0xCBxx for the extended instruction set.
+func lookup(opcode uint16) *instruction {
+	if opcode&0xFF00 == 0xCB00 {
+		return &cbInstructions[opcode&0xFF]
+	}
+	return &instructions[opcode&0xFF]
+}
+
+// trace adds an instruction to the history.
+func (c *CPU) trace(pc, opcode uint16) {
+	c.history[c.historyNext] = Trace{PC: pc, Opcode: opcode}
+	c.historyNext++
+	if c.historyNext == historySize {
+		c.historyNext = 0
+		c.historyFull = true
+	}
+}
+
+// traces returns the history, the oldest instruction first.
+func (c *CPU) traces() []Trace {
+	var h []Trace
+	if c.historyFull {
+		h = append(h, c.history[c.historyNext:]...)
+	}
+	h = append(h, c.history[:c.historyNext]...)
+	// Names are only looked up here to keep tracing cheap.
+	for i := range h {
+		h[i].Name = lookup(h[i].Opcode).name
+	}
+	return h
+}
+
+// serviceInterrupt jumps to the handler of the pending interrupt
+// with the highest priority and returns true when it does so.
+func (c *CPU) serviceInterrupt() bool {
+	k, ok := c.irq.Pending()
+	if !ok {
+		return false
+	}
+	// A pending interrupt always wakes up the CPU, even
+	// when it's not going to be serviced.
+	c.halted = false
+	if !c.ime {
+		return false
+	}
+	c.ime = false
+	c.irq.Clear(k)
+	if c.haltBug {
+		// IME was set by an EI right before HALT: the handler
+		// returns to HALT, which is executed again.
+		c.haltBug = false
+		c.regs.PC--
+	}
+	push(c, c.regs.PC)
+	c.regs.PC = k.Vector()
+	// Dispatching takes 5 machine cycles.
+	c.wait = 20 - 1
+	return true
 }
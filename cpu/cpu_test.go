@@ -2,13 +2,17 @@ package cpu
 
 import (
 	"testing"
+	"time"
 
+	"github.com/andreaperizzato/gameboy/interrupts"
+	"github.com/andreaperizzato/gameboy/memory"
+	"github.com/andreaperizzato/gameboy/model"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestCPU_Tick(t *testing.T) {
 	mem := make(simpleRAM, 0xFFFF)
-	c := NewGBC(mem)
+	c := New(model.DMG, mem, interrupts.New())
 
 	// Test main struction set.
 	c.regs.B = 0x00
@@ -30,7 +34,252 @@ func TestCPU_Tick(t *testing.T) {
 
 	// Test unknown instruction.
 	mem[0x0003] = 0xFD // this opcode doesn't exist.
-	assert.Panics(t, func() { c.Tick() })
+	assert.NotPanics(t, func() { c.Tick() })
+	assert.Error(t, c.Err(), "locked up")
+}
+
+func TestCPU_Lockup(t *testing.T) {
+	mem := make(simpleRAM, 0xFFFF)
+	irq := interrupts.New()
+	c := New(model.DMG, mem, irq)
+	c.regs.PC = 0x0100
+	c.regs.SP = 0xD000
+	copy(mem[0x0100:], []uint8{
+		0x00,       // NOP
+		0xCB, 0x37, // SWAP A
+		0xD3, // illegal
+	})
+	tick(c, 4+8)
+	assert.NoError(t, c.Err())
+	c.Tick()
+
+	err, ok := c.Err().(*LockupError)
+	if !assert.True(t, ok, "error type") {
+		return
+	}
+	assert.Equal(t, uint8(0xD3), err.Opcode, "opcode")
+	assert.Equal(t, uint16(0x0103), err.PC, "PC")
+	assert.Equal(t, []Trace{
+		{PC: 0x0100, Opcode: 0x0000, Name: "NOP"},
+		{PC: 0x0101, Opcode: 0xCB37, Name: "SWAP A"},
+	}, err.History, "history")
+	assert.EqualError(t, err, "cpu locked up by illegal opcode 0xD3 at 0x0103")
+
+	// The CPU doesn't do anything else, not even servicing interrupts.
+	c.ime = true
+	irq.Write(0xFFFF, 0xFF)
+	irq.Request(interrupts.VBlank)
+	tick(c, 100)
+	assert.Equal(t, uint16(0x0104), c.regs.PC, "PC")
+	assert.Equal(t, uint16(0xD000), c.regs.SP, "SP")
+}
+
+func TestCPU_LockupHistory(t *testing.T) {
+	mem := make(simpleRAM, 0xFFFF)
+	c := New(model.DMG, mem, interrupts.New())
+	// A long sequence of NOPs, only the last ones are kept.
+	mem[0x0020] = 0xFD
+	tick(c, 0x20*4+1)
+
+	err := c.Err().(*LockupError)
+	assert.Len(t, err.History, historySize)
+	assert.Equal(t, uint16(0x0020-historySize), err.History[0].PC, "oldest")
+	assert.Equal(t, uint16(0x001F), err.History[historySize-1].PC, "newest")
+}
+
+func TestCPU_Interrupts(t *testing.T) {
+	mem := make(simpleRAM, 0xFFFF)
+	irq := interrupts.New()
+	c := New(model.DMG, mem, irq)
+	c.regs.PC = 0x0100
+	c.regs.SP = 0xD000
+	c.ime = true
+	irq.Write(0xFFFF, 0xFF)
+
+	irq.Request(interrupts.Joypad)
+	irq.Request(interrupts.Timer)
+	c.Tick()
+	// The timer has higher priority than the joypad.
+	assert.Equal(t, uint16(0x0050), c.regs.PC, "PC")
+	assert.Equal(t, uint16(0xCFFE), c.regs.SP, "SP")
+	assert.Equal(t, uint8(0x00), mem[0xCFFE], "Stack - low nibble")
+	assert.Equal(t, uint8(0x01), mem[0xCFFF], "Stack - high nibble")
+	assert.False(t, c.ime, "IME is reset")
+	assert.Equal(t, uint8(0xF0), irq.Read(0xFF0F), "IF")
+
+	// Dispatching takes 20 cycles and the handler is a RETI.
+	mem[0x0050] = 0xD9
+	tick(c, 19)
+	assert.Equal(t, uint16(0x0050), c.regs.PC, "PC")
+	c.Tick()
+	assert.Equal(t, uint16(0x0100), c.regs.PC, "PC after RETI")
+	assert.True(t, c.ime, "IME after RETI")
+	tick(c, 15)
+
+	// The joypad interrupt is still pending.
+	c.Tick()
+	assert.Equal(t, uint16(0x0060), c.regs.PC, "PC")
+}
+
+func TestCPU_EIDelay(t *testing.T) {
+	mem := make(simpleRAM, 0xFFFF)
+	irq := interrupts.New()
+	c := New(model.DMG, mem, irq)
+	irq.Write(0xFFFF, 0xFF)
+	irq.Request(interrupts.VBlank)
+	c.regs.SP = 0xD000
+
+	mem[0x0000] = 0xFB // EI
+	mem[0x0001] = 0x00 // NOP
+	mem[0x0002] = 0x00 // NOP
+
+	tick(c, 4)
+	assert.Equal(t, uint16(0x0001), c.regs.PC, "EI")
+	// The instruction after EI is executed before servicing the interrupt.
+	tick(c, 4)
+	assert.Equal(t, uint16(0x0002), c.regs.PC, "NOP")
+	c.Tick()
+	assert.Equal(t, uint16(0x0040), c.regs.PC, "interrupt")
+}
+
+func TestCPU_HaltWakeUp(t *testing.T) {
+	mem := make(simpleRAM, 0xFFFF)
+	irq := interrupts.New()
+	c := New(model.DMG, mem, irq)
+	mem[0x0000] = 0x76 // HALT
+	mem[0x0001] = 0x04 // INC B
+
+	tick(c, 4)
+	tick(c, 100)
+	assert.Equal(t, uint16(0x0001), c.regs.PC, "halted")
+
+	// With IME reset, the CPU wakes up without servicing the interrupt.
+	irq.Write(0xFFFF, 0xFF)
+	irq.Request(interrupts.VBlank)
+	c.Tick()
+	assert.Equal(t, uint16(0x0002), c.regs.PC, "PC")
+	assert.Equal(t, uint8(0x01), c.regs.B, "B")
+}
+
+func TestCPU_HaltBug(t *testing.T) {
+	mem := make(simpleRAM, 0xFFFF)
+	irq := interrupts.New()
+	c := New(model.DMG, mem, irq)
+	irq.Write(0xFFFF, 0xFF)
+	irq.Request(interrupts.VBlank)
+	mem[0x0000] = 0x76 // HALT
+	mem[0x0001] = 0x04 // INC B
+
+	tick(c, 4)
+	// INC B is executed twice as PC fails to increment after the first fetch.
+	tick(c, 4)
+	assert.Equal(t, uint16(0x0001), c.regs.PC, "PC")
+	tick(c, 4)
+	assert.Equal(t, uint16(0x0002), c.regs.PC, "PC")
+	assert.Equal(t, uint8(0x02), c.regs.B, "B")
+}
+
+func TestCPU_StopWakeUp(t *testing.T) {
+	mem := make(simpleRAM, 0xFFFF)
+	irq := interrupts.New()
+	c := New(model.DMG, mem, irq)
+	mem[0x0000] = 0x10 // STOP
+	mem[0x0002] = 0x04 // INC B
+
+	tick(c, 4)
+	// Other interrupts don't wake up the CPU.
+	irq.Request(interrupts.Timer)
+	tick(c, 100)
+	assert.Equal(t, uint16(0x0002), c.regs.PC, "stopped")
+
+	irq.Request(interrupts.Joypad)
+	c.Tick()
+	assert.Equal(t, uint16(0x0003), c.regs.PC, "PC")
+	assert.Equal(t, uint8(0x01), c.regs.B, "B")
+}
+
+func TestCPU_SkipBoot(t *testing.T) {
+	tests := []struct {
+		name     string
+		model    model.Model
+		checksum uint8
+		expAF    uint16
+		expBC    uint16
+		expDE    uint16
+		expHL    uint16
+	}{
+		{"DMG", model.DMG, 0x00, 0x0180, 0x0013, 0x00D8, 0x014D},
+		{"DMG checksum", model.DMG, 0x42, 0x01B0, 0x0013, 0x00D8, 0x014D},
+		{"MGB", model.MGB, 0x42, 0xFFB0, 0x0013, 0x00D8, 0x014D},
+		{"SGB", model.SGB, 0x42, 0x0100, 0x0014, 0x0000, 0xC060},
+		{"CGB", model.CGB, 0x42, 0x1180, 0x0000, 0xFF56, 0x000D},
+		{"DMG0", model.DMG0, 0x42, 0x0100, 0xFF13, 0x00C1, 0x8403},
+		{"AGB", model.AGB, 0x42, 0x1100, 0x0100, 0xFF56, 0x000D},
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			c := New(model.DMG, make(simpleRAM, 0xFFFF), interrupts.New())
+			c.SkipBoot(tC.model, tC.checksum)
+			assert.Equal(t, tC.expAF, regAF.get(c), "AF")
+			assert.Equal(t, tC.expBC, regBC.get(c), "BC")
+			assert.Equal(t, tC.expDE, regDE.get(c), "DE")
+			assert.Equal(t, tC.expHL, regHL.get(c), "HL")
+			assert.Equal(t, uint16(0xFFFE), c.regs.SP, "SP")
+			assert.Equal(t, uint16(0x0100), c.regs.PC, "PC")
+		})
+	}
+}
+
+// newProgramCPU returns a CPU with the DMG memory map
+// which is about to run the program at 0x0100.
+func newProgramCPU(program []uint8) *CPU {
+	rom := make([]uint8, 0x8000)
+	copy(rom[0x0100:], program)
+	irq := interrupts.New()
+	spaces := append([]memory.AddressSpace{irq}, memory.NewDMGMap(model.DMG, memory.NewROM(rom, 0)).Spaces()...)
+	mmu := memory.NewMMU(memory.NewDMGBootROM(), spaces...)
+	c := New(model.DMG, mmu, irq)
+	c.regs.PC = 0x0100
+	return c
+}
+
+func TestCPU_TickDoesNotAllocate(t *testing.T) {
+	c := newProgramCPU([]uint8{
+		0x31, 0xF0, 0xDF, // LD SP,0xDFF0
+		0x21, 0x00, 0xC0, // LD HL,0xC000
+		0x7E,       // LD A,(HL)
+		0xCB, 0x37, // SWAP A
+		0x77,       // LD (HL),A
+		0xC5,       // PUSH BC
+		0xD1,       // POP DE
+		0x18, 0xF8, // JR -8
+	})
+	tick(c, 24)
+
+	// Each run goes through the 6 instructions of the loop, which take 64 cycles.
+	allocs := testing.AllocsPerRun(100, func() { tick(c, 64) })
+	assert.Equal(t, float64(0), allocs)
+	assert.Equal(t, uint16(0x0106), c.regs.PC, "back to the loop start")
+}
+
+func BenchmarkCPU_Tick(b *testing.B) {
+	c := newProgramCPU([]uint8{
+		0x21, 0x00, 0xC0, // LD HL,0xC000
+		0x7E,       // LD A,(HL)
+		0x3C,       // INC A
+		0x77,       // LD (HL),A
+		0x18, 0xFB, // JR -5
+	})
+	tick(c, 12)
+
+	// Each iteration runs the 4 instructions of the loop, which take 32 cycles.
+	b.ReportAllocs()
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		tick(c, 32)
+	}
+	b.ReportMetric(float64(4*b.N)/time.Since(start).Seconds(), "instr/s")
 }
 
 func tick(c *CPU, times int) {
@@ -1,10 +1,11 @@
 package cpu
 
+import "github.com/andreaperizzato/gameboy/memory"
+
 func inc8(reg reg8) runnable {
 	return func(c *CPU) uint8 {
 		// Flags: Z 0 H -
-		set, get := reg(c)
-		set(add(c, get(), 1, true))
+		reg.set(c, add(c, reg.get(c), 1, true))
 		return 4
 	}
 }
@@ -12,8 +13,7 @@ func inc8(reg reg8) runnable {
 func inc16(reg reg16) runnable {
 	return func(c *CPU) uint8 {
 		// Flags: - - - -
-		set, get := reg(c)
-		set(get() + 1)
+		reg.set(c, reg.get(c)+1)
 		return 8
 	}
 }
@@ -21,8 +21,7 @@ func inc16(reg reg16) runnable {
 func inc16Ref(reg reg16) runnable {
 	return func(c *CPU) uint8 {
 		// Flags: Z 0 H -
-		_, get := reg(c)
-		addr := get()
+		addr := reg.get(c)
 		v := add(c, c.mem.Read(addr), 1, true)
 		c.mem.Write(addr, v)
 		return 12
@@ -32,8 +31,7 @@ func inc16Ref(reg reg16) runnable {
 func dec8(reg reg8) runnable {
 	return func(c *CPU) uint8 {
 		// Flags: Z 1 H -
-		set, get := reg(c)
-		set(sub(c, get(), 1, true))
+		reg.set(c, sub(c, reg.get(c), 1, true))
 		return 4
 	}
 }
@@ -41,8 +39,7 @@ func dec8(reg reg8) runnable {
 func dec16Ref(reg reg16) runnable {
 	return func(c *CPU) uint8 {
 		// Flags: Z 1 H -
-		_, get := reg(c)
-		addr := get()
+		addr := reg.get(c)
 		v := sub(c, c.mem.Read(addr), 1, true)
 		c.mem.Write(addr, v)
 		return 12
@@ -52,36 +49,31 @@ func dec16Ref(reg reg16) runnable {
 func dec16(reg reg16) runnable {
 	return func(c *CPU) uint8 {
 		// Flags: - - - -
-		set, get := reg(c)
-		set(get() - 1)
+		reg.set(c, reg.get(c)-1)
 		return 8
 	}
 }
 
 func ld8Const(reg reg8) runnable {
 	return func(c *CPU) uint8 {
-		set, _ := reg(c)
-		set(nextArg(c))
+		reg.set(c, nextArg(c))
 		return 8
 	}
 }
 
 func ld16Const(reg reg16) runnable {
 	return func(c *CPU) uint8 {
-		set, _ := reg(c)
 		low := nextArg(c)
 		high := nextArg(c)
 		v := uint16(low) | (uint16(high) << 8)
-		set(v)
-		return 8
+		reg.set(c, v)
+		return 12
 	}
 }
 
 func ld88(dst, src reg8) runnable {
 	return func(c *CPU) uint8 {
-		_, get := src(c)
-		set, _ := dst(c)
-		set(get())
+		dst.set(c, src.get(c))
 		return 4
 	}
 }
@@ -107,12 +99,10 @@ func jr(flag func(c *CPU) bool, condition bool) runnable {
 // loading in A the byte in memory at the address pointed by HL.
 func ld816Ref(reg reg8, ptr reg16, offset int16) runnable {
 	return func(c *CPU) uint8 {
-		setPtr, getPtr := ptr(c)
-		v := c.mem.Read(getPtr())
-		setReg, _ := reg(c)
-		setReg(v)
-		newPtr := uint16(int16(getPtr()) + offset)
-		setPtr(newPtr)
+		v := c.mem.Read(ptr.get(c))
+		reg.set(c, v)
+		newPtr := uint16(int16(ptr.get(c)) + offset)
+		ptr.set(c, newPtr)
 		return 8
 	}
 }
@@ -122,11 +112,9 @@ func ld816Ref(reg reg8, ptr reg16, offset int16) runnable {
 // and then increments HL by the given offset.
 func ld16Ref8(ptr reg16, src reg8, offset int16) runnable {
 	return func(c *CPU) uint8 {
-		_, getSrc := src(c)
-		setPtr, getPtr := ptr(c)
-		c.mem.Write(getPtr(), getSrc())
-		newPtr := uint16(int16(getPtr()) + offset)
-		setPtr(newPtr)
+		c.mem.Write(ptr.get(c), src.get(c))
+		newPtr := uint16(int16(ptr.get(c)) + offset)
+		ptr.set(c, newPtr)
 		return 8
 	}
 }
@@ -135,9 +123,8 @@ func ld16Ref8(ptr reg16, src reg8, offset int16) runnable {
 // sometimes also called `LD (n),A` or `LDH (n),A`.
 func ld8ConstRef8(src reg8) runnable {
 	return func(c *CPU) uint8 {
-		_, get := src(c)
 		offset := nextArg(c)
-		c.mem.Write(0xFF00+uint16(offset), get())
+		c.mem.Write(0xFF00+uint16(offset), src.get(c))
 		return 12
 	}
 }
@@ -145,9 +132,7 @@ func ld8ConstRef8(src reg8) runnable {
 // ld8Ref8 implements instructions like `LD (0x00FF+C),A`.
 func ld8Ref8(dst, src reg8) runnable {
 	return func(c *CPU) uint8 {
-		_, getSrc := src(c)
-		_, getDst := dst(c)
-		c.mem.Write(0xFF00+uint16(getDst()), getSrc())
+		c.mem.Write(0xFF00+uint16(dst.get(c)), src.get(c))
 		return 8
 	}
 }
@@ -155,11 +140,10 @@ func ld8Ref8(dst, src reg8) runnable {
 // ld16ConstRef8 implements instructions as 'LD (nn),A'.
 func ld16ConstRef8(reg reg8) runnable {
 	return func(c *CPU) uint8 {
-		_, get := reg(c)
 		low := nextArg(c)
 		high := nextArg(c)
 		v := uint16(low) | (uint16(high) << 8)
-		c.mem.Write(v, get())
+		c.mem.Write(v, reg.get(c))
 		return 16
 	}
 }
@@ -170,17 +154,33 @@ func ld88ConstRef(reg reg8) runnable {
 	return func(c *CPU) uint8 {
 		offset := nextArg(c)
 		v := c.mem.Read(0xFF00 + uint16(offset))
-		set, _ := reg(c)
-		set(v)
+		reg.set(c, v)
 		return 12
 	}
 }
 
+// ld88Ref implements instructions like `LD A,(0x00FF+C)`.
+func ld88Ref(dst, src reg8) runnable {
+	return func(c *CPU) uint8 {
+		dst.set(c, c.mem.Read(0xFF00+uint16(src.get(c))))
+		return 8
+	}
+}
+
+// ld816ConstRef implements instructions as 'LD A,(nn)'.
+func ld816ConstRef(reg reg8) runnable {
+	return func(c *CPU) uint8 {
+		low := nextArg(c)
+		high := nextArg(c)
+		reg.set(c, c.mem.Read(uint16(low)|(uint16(high)<<8)))
+		return 16
+	}
+}
+
 // ld16RefConst implements instructions as 'LD (HL),n'.
 func ld16RefConst(reg reg16) runnable {
 	return func(c *CPU) uint8 {
-		_, get := reg(c)
-		c.mem.Write(get(), nextArg(c))
+		c.mem.Write(reg.get(c), nextArg(c))
 		return 12
 	}
 }
@@ -190,8 +190,7 @@ func ld16RefConst(reg reg16) runnable {
 func add16Ref(reg reg16) runnable {
 	return func(c *CPU) uint8 {
 		// Flags: Z 0 H C
-		_, get := reg(c)
-		c.regs.A = add(c, c.regs.A, c.mem.Read(get()), false)
+		c.regs.A = add(c, c.regs.A, c.mem.Read(reg.get(c)), false)
 		return 8
 	}
 }
@@ -203,13 +202,139 @@ func add16(dst reg16, src reg16) runnable {
 		// Flags: - 0 H C
 		c.flags.N = false
 
-		_, getSrc := src(c)
-		setDst, getDst := dst(c)
-		a := getSrc()
-		b := getDst()
+		a := src.get(c)
+		b := dst.get(c)
 		c.flags.C = uint32(a)+uint32(b) > 0xFFFF
-		c.flags.H = a&0x0F00+b&0x0F00 == 0x1000
-		setDst(a + b)
+		// Half carry happens on the 11th bit.
+		c.flags.H = a&0x0FFF+b&0x0FFF > 0x0FFF
+		dst.set(c, a+b)
+		return 8
+	}
+}
+
+// add8 implements instructions like 'ADD A,B'.
+func add8(reg reg8) runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 0 H C
+		c.regs.A = add(c, c.regs.A, reg.get(c), false)
+		return 4
+	}
+}
+
+// addConst implements 'ADD A,n'.
+func addConst() runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 0 H C
+		c.regs.A = add(c, c.regs.A, nextArg(c), false)
+		return 8
+	}
+}
+
+// adc8 implements instructions like 'ADC A,B',
+// adding to A the value of B plus the carry flag.
+func adc8(reg reg8) runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 0 H C
+		c.regs.A = adc(c, c.regs.A, reg.get(c))
+		return 4
+	}
+}
+
+// adc16Ref implements instructions like 'ADC A,(HL)'.
+func adc16Ref(reg reg16) runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 0 H C
+		c.regs.A = adc(c, c.regs.A, c.mem.Read(reg.get(c)))
+		return 8
+	}
+}
+
+// adcConst implements 'ADC A,n'.
+func adcConst() runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 0 H C
+		c.regs.A = adc(c, c.regs.A, nextArg(c))
+		return 8
+	}
+}
+
+func sub8(reg reg8) runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 1 H C
+		c.regs.A = sub(c, c.regs.A, reg.get(c), false)
+		return 4
+	}
+}
+
+// sub16Ref implements instructions like 'SUB (HL)'.
+func sub16Ref(reg reg16) runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 1 H C
+		c.regs.A = sub(c, c.regs.A, c.mem.Read(reg.get(c)), false)
+		return 8
+	}
+}
+
+// subConst implements 'SUB n'.
+func subConst() runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 1 H C
+		c.regs.A = sub(c, c.regs.A, nextArg(c), false)
+		return 8
+	}
+}
+
+// sbc8 implements instructions like 'SBC A,B',
+// subtracting from A the value of B plus the carry flag.
+func sbc8(reg reg8) runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 1 H C
+		c.regs.A = sbc(c, c.regs.A, reg.get(c))
+		return 4
+	}
+}
+
+// sbc16Ref implements instructions like 'SBC A,(HL)'.
+func sbc16Ref(reg reg16) runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 1 H C
+		c.regs.A = sbc(c, c.regs.A, c.mem.Read(reg.get(c)))
+		return 8
+	}
+}
+
+// sbcConst implements 'SBC A,n'.
+func sbcConst() runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 1 H C
+		c.regs.A = sbc(c, c.regs.A, nextArg(c))
+		return 8
+	}
+}
+
+// and8 implements instructions like 'AND B'.
+func and8(reg reg8) runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 0 1 0
+		c.regs.A = and(c, c.regs.A, reg.get(c))
+		return 4
+	}
+}
+
+// and16Ref implements instructions like 'AND (HL)'.
+func and16Ref(reg reg16) runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 0 1 0
+		c.regs.A = and(c, c.regs.A, c.mem.Read(reg.get(c)))
+		return 8
+	}
+}
+
+// andConst implements 'AND n'.
+func andConst() runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 0 1 0
+		c.regs.A = and(c, c.regs.A, nextArg(c))
 		return 8
 	}
 }
@@ -217,19 +342,62 @@ func add16(dst reg16, src reg16) runnable {
 func xor8(reg reg8) runnable {
 	return func(c *CPU) uint8 {
 		// Flags: Z 0 0 0
-		_, get := reg(c)
-		c.regs.A ^= get()
-		c.flags.Z = c.regs.A == 0
-		c.flags.N, c.flags.C, c.flags.H = false, false, false
+		c.regs.A = xor(c, c.regs.A, reg.get(c))
 		return 4
 	}
 }
 
-func sub8(reg reg8) runnable {
+// xor16Ref implements instructions like 'XOR (HL)'.
+func xor16Ref(reg reg16) runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 0 0 0
+		c.regs.A = xor(c, c.regs.A, c.mem.Read(reg.get(c)))
+		return 8
+	}
+}
+
+// xorConst implements 'XOR n'.
+func xorConst() runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 0 0 0
+		c.regs.A = xor(c, c.regs.A, nextArg(c))
+		return 8
+	}
+}
+
+// or8 implements instructions like 'OR B'.
+func or8(reg reg8) runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 0 0 0
+		c.regs.A = or(c, c.regs.A, reg.get(c))
+		return 4
+	}
+}
+
+// or16Ref implements instructions like 'OR (HL)'.
+func or16Ref(reg reg16) runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 0 0 0
+		c.regs.A = or(c, c.regs.A, c.mem.Read(reg.get(c)))
+		return 8
+	}
+}
+
+// orConst implements 'OR n'.
+func orConst() runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 0 0 0
+		c.regs.A = or(c, c.regs.A, nextArg(c))
+		return 8
+	}
+}
+
+// cp8 implements instructions like 'CP B'.
+// It computes A-B to set the flags and discards the result.
+func cp8(reg reg8) runnable {
 	return func(c *CPU) uint8 {
 		// Flags: Z 1 H C
-		_, get := reg(c)
-		c.regs.A = sub(c, c.regs.A, get(), true)
+		_ = sub(c, c.regs.A, reg.get(c), false)
 		return 4
 	}
 }
@@ -237,8 +405,7 @@ func sub8(reg reg8) runnable {
 func cp16Ref(reg reg16) runnable {
 	return func(c *CPU) uint8 {
 		// Flags: Z 1 H C
-		_, get := reg(c)
-		v := c.mem.Read(get())
+		v := c.mem.Read(reg.get(c))
 		_ = sub(c, c.regs.A, v, false)
 		return 8
 	}
@@ -255,34 +422,88 @@ func cpConst() runnable {
 
 func pop16(reg reg16) runnable {
 	return func(c *CPU) uint8 {
-		set, _ := reg(c)
-		set(pop(c))
+		reg.set(c, pop(c))
 		return 12
 	}
 }
 
 func push16(reg reg16) runnable {
 	return func(c *CPU) uint8 {
-		_, get := reg(c)
-		push(c, get())
+		push(c, reg.get(c))
 		return 16
 	}
 }
 
-func ret() runnable {
+// ret implements conditional and normal returns.
+// set flag = nil to ignore the condition.
+func ret(flag func(c *CPU) bool, condition bool) runnable {
+	return func(c *CPU) uint8 {
+		if flag == nil {
+			c.regs.PC = pop(c)
+			return 16
+		}
+		if flag(c) == condition {
+			c.regs.PC = pop(c)
+			return 20
+		}
+		return 8
+	}
+}
+
+// reti returns and enables interrupts.
+func reti() runnable {
 	return func(c *CPU) uint8 {
 		c.regs.PC = pop(c)
+		c.ime = true
 		return 16
 	}
 }
 
-func call() runnable {
+// call implements conditional and normal calls.
+// set flag = nil to ignore the condition.
+func call(flag func(c *CPU) bool, condition bool) runnable {
 	return func(c *CPU) uint8 {
 		low := nextArg(c)
 		high := nextArg(c)
+		if flag == nil || flag(c) == condition {
+			push(c, c.regs.PC)
+			c.regs.PC = uint16(low) | uint16(high)<<8
+			return 24
+		}
+		return 12
+	}
+}
+
+// rst implements instructions like 'RST 38H',
+// calling the routine at a fixed address in page zero.
+func rst(addr uint16) runnable {
+	return func(c *CPU) uint8 {
 		push(c, c.regs.PC)
-		c.regs.PC = uint16(low) | uint16(high)<<8
-		return 24
+		c.regs.PC = addr
+		return 16
+	}
+}
+
+// jp implements conditional and normal absolute jumps.
+// set flag = nil to ignore the condition.
+func jp(flag func(c *CPU) bool, condition bool) runnable {
+	return func(c *CPU) uint8 {
+		low := nextArg(c)
+		high := nextArg(c)
+		if flag == nil || flag(c) == condition {
+			c.regs.PC = uint16(low) | uint16(high)<<8
+			return 16
+		}
+		return 12
+	}
+}
+
+// jp16 implements 'JP (HL)' which, despite the name,
+// jumps to the address in HL and not the one it points to.
+func jp16(reg reg16) runnable {
+	return func(c *CPU) uint8 {
+		c.regs.PC = reg.get(c)
+		return 4
 	}
 }
 
@@ -290,24 +511,22 @@ func call() runnable {
 func bit8(pos uint8, reg reg8) runnable {
 	return func(c *CPU) uint8 {
 		// Flags: Z 0 1 -
-		_, get := reg(c)
 		c.flags.N = false
 		c.flags.H = true
-		c.flags.Z = (1<<pos)&get() == 0
+		c.flags.Z = (1<<pos)&reg.get(c) == 0
 		return 8
 	}
 }
 
-// rl8 implements instuctions like 'RL A'.
-// Rotates A to the left through the carry flag.
+// rl8 implements instuctions like 'RL B'.
+// Rotates B to the left through the carry flag.
 func rl8(reg reg8) runnable {
 	return func(c *CPU) uint8 {
 		// Flags: Z 0 0 C
 		c.flags.N = false
 		c.flags.H = false
 
-		set, get := reg(c)
-		v := get()
+		v := reg.get(c)
 		res := (v << 1) & 0xFF
 		if c.flags.C {
 			res |= 1
@@ -315,21 +534,20 @@ func rl8(reg reg8) runnable {
 		// If the 7th bit was set, carry will happen.
 		c.flags.C = v&(1<<7) > 0
 		c.flags.Z = res == 0
-		set(res)
+		reg.set(c, res)
 		return 8
 	}
 }
 
-// rr8 implements instuctions like 'RR A'.
-// Rotates A to the right through the carry flag.
+// rr8 implements instuctions like 'RR B'.
+// Rotates B to the right through the carry flag.
 func rr8(reg reg8) runnable {
 	return func(c *CPU) uint8 {
 		// Flags: Z 0 0 C
 		c.flags.N = false
 		c.flags.H = false
 
-		set, get := reg(c)
-		v := get()
+		v := reg.get(c)
 		res := v >> 1
 		if c.flags.C {
 			res |= 0x80
@@ -337,47 +555,154 @@ func rr8(reg reg8) runnable {
 		// If the 1st bit was set, carry will happen.
 		c.flags.C = v&(0x01) > 0
 		c.flags.Z = res == 0
-		set(res)
+		reg.set(c, res)
 		return 8
 	}
 }
 
-// rlc8 implements instuctions like 'RLCA'.
-// Rotates A to the left with bit 7 being moved to bit 0 and also stored into the carry.
+// rlc8 implements instuctions like 'RLC B'.
+// Rotates B to the left with bit 7 being moved to bit 0 and also stored into the carry.
 func rlc8(reg reg8) runnable {
 	return func(c *CPU) uint8 {
-		// Flags: 0 0 0 C
-		c.flags.Z = false
+		// Flags: Z 0 0 C
 		c.flags.N = false
 		c.flags.H = false
 
-		set, get := reg(c)
-		v := get()
+		v := reg.get(c)
 		carry := (v & 0x80) >> 7
 		c.flags.C = carry == 1
-		set(v<<1 | carry)
-		return 4
+		res := v<<1 | carry
+		c.flags.Z = res == 0
+		reg.set(c, res)
+		return 8
 	}
 }
 
-// rrc8 implements instuctions like 'RRCA'.
-// Rotates A to the right with bit 0 being moved to bit 7 and also stored into the carry.
+// rrc8 implements instuctions like 'RRC B'.
+// Rotates B to the right with bit 0 being moved to bit 7 and also stored into the carry.
 func rrc8(reg reg8) runnable {
 	return func(c *CPU) uint8 {
-		// Flags: 0 0 0 C
-		c.flags.Z = false
+		// Flags: Z 0 0 C
 		c.flags.N = false
 		c.flags.H = false
 
-		set, get := reg(c)
-		v := get()
+		v := reg.get(c)
 		carry := (v & 0x01)
 		c.flags.C = carry == 1
-		set(v>>1 | carry<<7)
+		res := v>>1 | carry<<7
+		c.flags.Z = res == 0
+		reg.set(c, res)
+		return 8
+	}
+}
+
+// rotA turns a rotation of A such as 'RLC A' into its
+// one-byte version 'RLCA' which is faster and always resets Z.
+func rotA(rot runnable) runnable {
+	return func(c *CPU) uint8 {
+		// Flags: 0 0 0 C
+		rot(c)
+		c.flags.Z = false
 		return 4
 	}
 }
 
+// sla8 implements instructions like 'SLA B'.
+// Shifts B to the left into the carry, bit 0 is reset.
+func sla8(reg reg8) runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 0 0 C
+		c.flags.N = false
+		c.flags.H = false
+
+		v := reg.get(c)
+		c.flags.C = v&0x80 > 0
+		res := v << 1
+		c.flags.Z = res == 0
+		reg.set(c, res)
+		return 8
+	}
+}
+
+// sra8 implements instructions like 'SRA B'.
+// Shifts B to the right into the carry, bit 7 is unchanged.
+func sra8(reg reg8) runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 0 0 C
+		c.flags.N = false
+		c.flags.H = false
+
+		v := reg.get(c)
+		c.flags.C = v&0x01 > 0
+		res := v>>1 | v&0x80
+		c.flags.Z = res == 0
+		reg.set(c, res)
+		return 8
+	}
+}
+
+// srl8 implements instructions like 'SRL B'.
+// Shifts B to the right into the carry, bit 7 is reset.
+func srl8(reg reg8) runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 0 0 C
+		c.flags.N = false
+		c.flags.H = false
+
+		v := reg.get(c)
+		c.flags.C = v&0x01 > 0
+		res := v >> 1
+		c.flags.Z = res == 0
+		reg.set(c, res)
+		return 8
+	}
+}
+
+// swap8 implements instructions like 'SWAP B'.
+// Swaps the high and low nibbles of B.
+func swap8(reg reg8) runnable {
+	return func(c *CPU) uint8 {
+		// Flags: Z 0 0 0
+		c.flags.N = false
+		c.flags.H = false
+		c.flags.C = false
+
+		v := reg.get(c)
+		res := v<<4 | v>>4
+		c.flags.Z = res == 0
+		reg.set(c, res)
+		return 8
+	}
+}
+
+// res8 implements instructions like 'RES 7,A'.
+func res8(pos uint8, reg reg8) runnable {
+	return func(c *CPU) uint8 {
+		// Flags: - - - -
+		reg.set(c, reg.get(c)&^(1<<pos))
+		return 8
+	}
+}
+
+// set8 implements instructions like 'SET 7,A'.
+func set8(pos uint8, reg reg8) runnable {
+	return func(c *CPU) uint8 {
+		// Flags: - - - -
+		reg.set(c, reg.get(c)|(1<<pos))
+		return 8
+	}
+}
+
+// withCycles overrides the cycles taken by an instruction.
+// It's needed by variants such as 'RL (HL)' which do the same
+// work as 'RL B' but take longer because of the memory access.
+func withCycles(cycles uint8, run runnable) runnable {
+	return func(c *CPU) uint8 {
+		run(c)
+		return cycles
+	}
+}
+
 // Complement.
 func cpl8(reg reg8) runnable {
 	return func(c *CPU) uint8 {
@@ -385,8 +710,7 @@ func cpl8(reg reg8) runnable {
 		c.flags.N = true
 		c.flags.H = true
 
-		set, get := reg(c)
-		set(^get())
+		reg.set(c, ^reg.get(c))
 		return 4
 	}
 }
@@ -396,7 +720,7 @@ func cpl8(reg reg8) runnable {
 func add(c *CPU, a, b uint8, ignoreCarry bool) uint8 {
 	c.flags.N = false
 	// https://robdor.com/2016/08/10/gameboy-emulator-half-carry-flag/
-	c.flags.H = a&0x0F+b&0x0F == 0x10
+	c.flags.H = a&0x0F+b&0x0F > 0x0F
 	if !ignoreCarry {
 		// if the sum is greater than 0xFF, overflow will occur.
 		c.flags.C = uint16(a)+uint16(b) > 0xFF
@@ -422,17 +746,128 @@ func sub(c *CPU, a, b uint8, ignoreCarry bool) uint8 {
 	return res
 }
 
+// adc computes a+b+carry and sets the flags accordingly.
+func adc(c *CPU, a, b uint8) uint8 {
+	carry := uint8(0)
+	if c.flags.C {
+		carry = 1
+	}
+	c.flags.N = false
+	c.flags.H = a&0x0F+b&0x0F+carry > 0x0F
+	c.flags.C = uint16(a)+uint16(b)+uint16(carry) > 0xFF
+	res := a + b + carry
+	c.flags.Z = res == 0
+	return res
+}
+
+// sbc computes a-b-carry and sets the flags accordingly.
+func sbc(c *CPU, a, b uint8) uint8 {
+	carry := uint8(0)
+	if c.flags.C {
+		carry = 1
+	}
+	c.flags.N = true
+	c.flags.H = uint16(b&0x0F)+uint16(carry) > uint16(a&0x0F)
+	c.flags.C = uint16(b)+uint16(carry) > uint16(a)
+	res := a - b - carry
+	c.flags.Z = res == 0
+	return res
+}
+
+// and computes a&b and sets the flags accordingly.
+func and(c *CPU, a, b uint8) uint8 {
+	res := a & b
+	c.flags.Z = res == 0
+	c.flags.N, c.flags.H, c.flags.C = false, true, false
+	return res
+}
+
+// or computes a|b and sets the flags accordingly.
+func or(c *CPU, a, b uint8) uint8 {
+	res := a | b
+	c.flags.Z = res == 0
+	c.flags.N, c.flags.H, c.flags.C = false, false, false
+	return res
+}
+
+// xor computes a^b and sets the flags accordingly.
+func xor(c *CPU, a, b uint8) uint8 {
+	res := a ^ b
+	c.flags.Z = res == 0
+	c.flags.N, c.flags.H, c.flags.C = false, false, false
+	return res
+}
+
+// addSP computes SP+n, where n is a signed byte, and sets the flags accordingly.
+// H and C are computed on the low byte as if it was an unsigned 8-bit addition.
+func addSP(c *CPU, n uint8) uint16 {
+	sp := c.regs.SP
+	c.flags.Z = false
+	c.flags.N = false
+	c.flags.H = sp&0x0F+uint16(n&0x0F) > 0x0F
+	c.flags.C = sp&0xFF+uint16(n) > 0xFF
+	return uint16(int32(sp) + int32(int8(n)))
+}
+
 func nop() runnable {
 	return func(c *CPU) uint8 {
 		return 4
 	}
 }
 
+// halt suspends the CPU until an interrupt is pending.
+func halt() runnable {
+	return func(c *CPU) uint8 {
+		if _, pending := c.irq.Pending(); pending && !c.ime {
+			// HALT bug: the CPU doesn't halt and fails
+			// to increment PC after reading the next opcode.
+			// https://gbdev.io/pandocs/#halt-bug
+			c.haltBug = true
+			return 4
+		}
+		c.halted = true
+		return 4
+	}
+}
+
+// di disables interrupts.
+func di() runnable {
+	return func(c *CPU) uint8 {
+		c.ime = false
+		c.eiDelay = 0
+		return 4
+	}
+}
+
+// ei enables interrupts after the next instruction.
+func ei() runnable {
+	return func(c *CPU) uint8 {
+		// The counter is decremented after each instruction
+		// including this one, see CPU.Tick.
+		c.eiDelay = 2
+		return 4
+	}
+}
+
+// stop puts the CPU in low power mode until a button is pressed.
+// On the CGB it switches speed instead, when one has been armed in KEY1.
 func stop() runnable {
 	return func(c *CPU) uint8 {
-		// Stop will put the CPU in low power mode
-		// and there is nothing to do here.
 		_ = nextArg(c) // stop has one ignored arg.
+		// Any write resets the divider.
+		c.mem.Write(divAddr, 0)
+		if c.model.IsCGB() && c.mem.Read(key1Addr)&key1Armed != 0 {
+			c.doubleSpeed = !c.doubleSpeed
+			key1 := uint8(0)
+			if c.doubleSpeed {
+				key1 = key1Speed
+			}
+			// The speed bit is read-only, the CPU sets it bypassing the bus.
+			memory.Poke(c.mem, key1Addr, key1)
+			c.pause = speedSwitchCycles
+			return 4
+		}
+		c.stopped = true
 		return 4
 	}
 }
@@ -448,6 +883,32 @@ func ld16ConstRefSP() runnable {
 	}
 }
 
+// ld1616 implements 'LD SP,HL'.
+func ld1616(dst, src reg16) runnable {
+	return func(c *CPU) uint8 {
+		dst.set(c, src.get(c))
+		return 8
+	}
+}
+
+// ld16SPOffset implements 'LD HL,SP+n' where n is a signed byte.
+func ld16SPOffset(reg reg16) runnable {
+	return func(c *CPU) uint8 {
+		// Flags: 0 0 H C
+		reg.set(c, addSP(c, nextArg(c)))
+		return 12
+	}
+}
+
+// addSPConst implements 'ADD SP,n' where n is a signed byte.
+func addSPConst() runnable {
+	return func(c *CPU) uint8 {
+		// Flags: 0 0 H C
+		c.regs.SP = addSP(c, nextArg(c))
+		return 16
+	}
+}
+
 // Decimal Adjust Accumulator to get a correct BCD representation after an arithmetic instruction.
 func daa() runnable {
 	return func(c *CPU) uint8 {
@@ -456,7 +917,9 @@ func daa() runnable {
 		// Implementation comes for here:
 		// https://forums.nesdev.com/viewtopic.php?t=15944
 
-		if !c.flags.Z {
+		// After an addition, the digits overflowing 9 are adjusted,
+		// after a subtraction only those which borrowed are.
+		if !c.flags.N {
 			if c.flags.C || c.regs.A > 0x99 {
 				c.regs.A += 0x60
 				c.flags.C = true
@@ -466,7 +929,7 @@ func daa() runnable {
 			}
 		} else {
 			if c.flags.C {
-				c.regs.A += 0x60
+				c.regs.A -= 0x60
 			}
 			if c.flags.H {
 				c.regs.A -= 0x06
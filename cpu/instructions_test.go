@@ -3,6 +3,8 @@ package cpu
 import (
 	"testing"
 
+	"github.com/andreaperizzato/gameboy/interrupts"
+	"github.com/andreaperizzato/gameboy/model"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -155,6 +157,36 @@ func TestInstructions_sub8(t *testing.T) {
 	}
 }
 
+func TestInstructions_daa(t *testing.T) {
+	tests := []struct {
+		name  string
+		a     uint8
+		flags flags
+		res   uint8
+		exp   flags
+	}{
+		{"add", 0x3C, flags{}, 0x42, flags{}},                                                      // 0x15 + 0x27
+		{"add with half carry", 0x11, flags{H: true}, 0x17, flags{}},                               // 0x09 + 0x08
+		{"add overflowing 99", 0x9A, flags{}, 0x00, flags{Z: true, C: true}},                       // 0x99 + 0x01
+		{"add with carry", 0x20, flags{C: true}, 0x80, flags{C: true}},                             // 0x90 + 0x90
+		{"sub", 0x2D, flags{N: true, H: true}, 0x27, flags{N: true}},                               // 0x42 - 0x15
+		{"sub with borrow", 0xEE, flags{N: true, H: true, C: true}, 0x88, flags{N: true, C: true}}, // 0x15 - 0x27
+		{"sub to zero", 0x00, flags{N: true}, 0x00, flags{Z: true, N: true}},                       // 0x15 - 0x15
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			c := &CPU{}
+			c.regs.A = tC.a
+			c.flags = tC.flags
+			cycles := daa()(c)
+
+			assert.EqualValues(t, 4, cycles, "cycles")
+			assert.Equal(t, tC.res, c.regs.A, "A")
+			assert.Equal(t, tC.exp, c.flags, "flags")
+		})
+	}
+}
+
 func TestInstructions_ld8Const(t *testing.T) {
 	c := &CPU{
 		regs: registers{
@@ -192,7 +224,7 @@ func TestInstructions_ld16Const(t *testing.T) {
 	c.regs.E = 0xEE
 	cycles := ld16Const(regDE)(c)
 
-	assert.EqualValues(t, 8, cycles, "cycles")
+	assert.EqualValues(t, 12, cycles, "cycles")
 	assert.EqualValues(t, 0x03, c.regs.PC, "PC")
 	assert.EqualValues(t, 0xBB, c.regs.E, "E")
 	assert.EqualValues(t, 0xCC, c.regs.D, "D")
@@ -391,7 +423,7 @@ func TestInstructions_add16(t *testing.T) {
 		flags flags
 	}{
 		{"carry", 0xFF02, 0xA001, 0x9F03, flags{C: true}},
-		{"no carry", 0x0C01, 0x0A02, 0x1603, flags{}},
+		{"no carry", 0x0C01, 0x0A02, 0x1603, flags{H: true}},
 		{"half carry", 0x0C10, 0x0420, 0x1030, flags{H: true}},
 		{"no half carry", 0x0C10, 0x0120, 0x0D30, flags{}},
 	}
@@ -412,6 +444,204 @@ func TestInstructions_add16(t *testing.T) {
 	}
 }
 
+func TestInstructions_add8(t *testing.T) {
+	tests := []struct {
+		name  string
+		a     uint8
+		b     uint8
+		res   uint8 // a + b
+		flags flags
+	}{
+		{"result is zero", 0xFE, 0x02, 0x00, flags{Z: true, C: true, H: true}},
+		{"half carry", 0x0F, 0x0F, 0x1E, flags{H: true}},
+		{"carry", 0xF0, 0x20, 0x10, flags{C: true}},
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			c := &CPU{}
+			c.regs.A = tC.a
+			c.regs.B = tC.b
+			cycles := add8(regB)(c)
+
+			assert.EqualValues(t, 4, cycles, "cycles")
+			assert.Equal(t, tC.res, c.regs.A, "A")
+			assert.Equal(t, tC.flags, c.flags, "flags")
+		})
+	}
+}
+
+func TestInstructions_adc8(t *testing.T) {
+	tests := []struct {
+		name  string
+		a     uint8
+		b     uint8
+		carry bool
+		res   uint8 // a + b + carry
+		flags flags
+	}{
+		{"result is zero", 0xFE, 0x01, true, 0x00, flags{Z: true, C: true, H: true}},
+		{"half carry from the carry flag", 0x0E, 0x01, true, 0x10, flags{H: true}},
+		{"no carry", 0x01, 0x02, false, 0x03, flags{}},
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			c := &CPU{}
+			c.flags.C = tC.carry
+			c.regs.A = tC.a
+			c.regs.B = tC.b
+			cycles := adc8(regB)(c)
+
+			assert.EqualValues(t, 4, cycles, "cycles")
+			assert.Equal(t, tC.res, c.regs.A, "A")
+			assert.Equal(t, tC.flags, c.flags, "flags")
+		})
+	}
+}
+
+func TestInstructions_sbc8(t *testing.T) {
+	tests := []struct {
+		name  string
+		a     uint8
+		b     uint8
+		carry bool
+		res   uint8 // a - b - carry
+		flags flags
+	}{
+		{"result is zero", 0x03, 0x02, true, 0x00, flags{Z: true, N: true}},
+		{"half carry from the carry flag", 0x10, 0x00, true, 0x0F, flags{N: true, H: true}},
+		{"carry", 0x00, 0x01, false, 0xFF, flags{N: true, H: true, C: true}},
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			c := &CPU{}
+			c.flags.C = tC.carry
+			c.regs.A = tC.a
+			c.regs.B = tC.b
+			cycles := sbc8(regB)(c)
+
+			assert.EqualValues(t, 4, cycles, "cycles")
+			assert.Equal(t, tC.res, c.regs.A, "A")
+			assert.Equal(t, tC.flags, c.flags, "flags")
+		})
+	}
+}
+
+func TestInstructions_and8(t *testing.T) {
+	c := &CPU{flags: flags{C: true, N: true}}
+	c.regs.A = 0b1100
+	c.regs.B = 0b0011
+
+	cycles := and8(regB)(c)
+
+	assert.EqualValues(t, 4, cycles, "cycles")
+	assert.EqualValues(t, 0x00, c.regs.A, "A")
+	assert.Equal(t, flags{Z: true, H: true}, c.flags, "flags")
+}
+
+func TestInstructions_or8(t *testing.T) {
+	c := &CPU{flags: flags{C: true, H: true}}
+	c.regs.A = 0b1100
+	c.regs.B = 0b0011
+
+	cycles := or8(regB)(c)
+
+	assert.EqualValues(t, 4, cycles, "cycles")
+	assert.EqualValues(t, 0b1111, c.regs.A, "A")
+	assert.Equal(t, flags{}, c.flags, "flags")
+}
+
+func TestInstructions_andConst(t *testing.T) {
+	c := &CPU{mem: simpleRAM{0x0F}}
+	c.regs.A = 0xAA
+
+	cycles := andConst()(c)
+
+	assert.EqualValues(t, 8, cycles, "cycles")
+	assert.EqualValues(t, 0x0A, c.regs.A, "A")
+	assert.EqualValues(t, 0x01, c.regs.PC, "PC")
+	assert.Equal(t, flags{H: true}, c.flags, "flags")
+}
+
+func TestInstructions_addSPConst(t *testing.T) {
+	tests := []struct {
+		name  string
+		sp    uint16
+		n     uint8
+		res   uint16
+		flags flags
+	}{
+		{"positive offset", 0x1000, 0x05, 0x1005, flags{}},
+		{"negative offset", 0x1000, 0xFF, 0x0FFF, flags{}},
+		{"carry from the low byte", 0x00FF, 0x01, 0x0100, flags{H: true, C: true}},
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			c := &CPU{mem: simpleRAM{tC.n}, flags: flags{Z: true, N: true}}
+			c.regs.SP = tC.sp
+
+			cycles := addSPConst()(c)
+
+			assert.EqualValues(t, 16, cycles, "cycles")
+			assert.Equal(t, tC.res, c.regs.SP, "SP")
+			assert.Equal(t, tC.flags, c.flags, "flags")
+		})
+	}
+}
+
+func TestInstructions_ld16SPOffset(t *testing.T) {
+	c := &CPU{mem: simpleRAM{0xFE}}
+	c.regs.SP = 0xAA02
+
+	cycles := ld16SPOffset(regHL)(c)
+
+	assert.EqualValues(t, 12, cycles, "cycles")
+	assert.EqualValues(t, 0xAA, c.regs.H, "H")
+	assert.EqualValues(t, 0x00, c.regs.L, "L")
+	assert.EqualValues(t, 0xAA02, c.regs.SP, "SP")
+	// 0x02+0xFE overflows both the low nibble and the low byte.
+	assert.Equal(t, flags{H: true, C: true}, c.flags, "flags")
+}
+
+func TestInstructions_ld816ConstRef(t *testing.T) {
+	mem := make(simpleRAM, 0xFFFF)
+	c := &CPU{mem: mem}
+	c.regs.PC = 0x0011
+	mem[0x0011] = 0x77
+	mem[0x0012] = 0x66
+	mem[0x6677] = 0xAA
+
+	cycles := ld816ConstRef(regA)(c)
+
+	assert.EqualValues(t, 16, cycles, "cycles")
+	assert.EqualValues(t, 0xAA, c.regs.A, "A")
+}
+
+func TestInstructions_ld88Ref(t *testing.T) {
+	mem := make(simpleRAM, 0xFFFF)
+	c := &CPU{mem: mem}
+	c.regs.C = 0xCC
+	mem[0xFFCC] = 0xAA
+
+	cycles := ld88Ref(regA, regC)(c)
+
+	assert.EqualValues(t, 8, cycles, "cycles")
+	assert.EqualValues(t, 0xAA, c.regs.A, "A")
+}
+
+func TestInstructions_ei_di(t *testing.T) {
+	c := &CPU{}
+	cycles := ei()(c)
+	assert.EqualValues(t, 4, cycles, "cycles")
+	assert.False(t, c.ime, "IME is set after the next instruction")
+	assert.NotZero(t, c.eiDelay, "EI delay")
+
+	c.ime = true
+	cycles = di()(c)
+	assert.EqualValues(t, 4, cycles, "cycles")
+	assert.False(t, c.ime, "IME after DI")
+	assert.Zero(t, c.eiDelay, "DI cancels a pending EI")
+}
+
 func TestInstructions_xor8(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -528,6 +758,39 @@ func TestInstructions_push16(t *testing.T) {
 	assert.Equal(t, uint8(0xBB), mem[0xAA01], "B")
 }
 
+func TestInstructions_push16AF(t *testing.T) {
+	mem := make(simpleRAM, 0xFFFF)
+	c := &CPU{mem: mem}
+	c.regs.SP = 0xAA02
+	c.regs.A = 0xBB
+	c.flags = flags{Z: true, C: true}
+
+	cycles := push16(regAF)(c)
+
+	assert.EqualValues(t, 16, cycles, "cycles")
+	assert.Equal(t, uint8(0x90), mem[0xAA00], "F")
+	assert.Equal(t, uint8(0xBB), mem[0xAA01], "A")
+}
+
+func TestInstructions_pop16AF(t *testing.T) {
+	mem := make(simpleRAM, 0xFFFF)
+	c := &CPU{mem: mem}
+	c.regs.SP = 0xAA00
+	mem[0xAA00] = 0x6F // the lowest nibble of F is ignored.
+	mem[0xAA01] = 0xBB
+
+	cycles := pop16(regAF)(c)
+
+	assert.EqualValues(t, 12, cycles, "cycles")
+	assert.Equal(t, uint8(0xBB), c.regs.A, "A")
+	assert.Equal(t, flags{N: true, H: true}, c.flags, "flags")
+
+	// Pushing it back gives F with the lowest nibble reset.
+	push16(regAF)(c)
+	assert.Equal(t, uint8(0x60), mem[0xAA00], "F")
+	assert.Equal(t, uint8(0xBB), mem[0xAA01], "A")
+}
+
 func TestInstructions_ret(t *testing.T) {
 	mem := make(simpleRAM, 0xFFFF)
 	c := &CPU{mem: mem}
@@ -535,7 +798,7 @@ func TestInstructions_ret(t *testing.T) {
 	mem[0x0AA00] = 0x11
 	mem[0x0AA01] = 0x22
 
-	cycles := ret()(c)
+	cycles := ret(nil, true)(c)
 
 	assert.EqualValues(t, 16, cycles, "cycles")
 	assert.Equal(t, uint16(0x2211), c.regs.PC, "PC")
@@ -549,7 +812,7 @@ func TestInstructions_call(t *testing.T) {
 	mem[0x1122] = 0x44 // these values are the args of the instructions
 	mem[0x1123] = 0x55 // and we'll be used to set PC.
 
-	cycles := call()(c)
+	cycles := call(nil, true)(c)
 
 	assert.EqualValues(t, 24, cycles, "cycles")
 	assert.EqualValues(t, 0x5544, c.regs.PC, "PC")
@@ -558,6 +821,115 @@ func TestInstructions_call(t *testing.T) {
 	assert.EqualValues(t, 0x11, mem[0xAA01], "Stack - high nibble") // high nibble of old PC
 }
 
+func TestInstructions_ret_conditional(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition bool
+		cycles    uint8
+		pc        uint16
+	}{
+		{"taken", true, 20, 0x2211},
+		{"not taken", false, 8, 0x0000},
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			mem := make(simpleRAM, 0xFFFF)
+			c := &CPU{mem: mem, flags: flags{Z: true}}
+			c.regs.SP = 0xAA00
+			mem[0xAA00] = 0x11
+			mem[0xAA01] = 0x22
+
+			cycles := ret(flagZ, tC.condition)(c)
+
+			assert.Equal(t, tC.cycles, cycles, "cycles")
+			assert.Equal(t, tC.pc, c.regs.PC, "PC")
+		})
+	}
+}
+
+func TestInstructions_reti(t *testing.T) {
+	mem := make(simpleRAM, 0xFFFF)
+	c := &CPU{mem: mem}
+	c.regs.SP = 0xAA00
+	mem[0xAA00] = 0x11
+	mem[0xAA01] = 0x22
+
+	cycles := reti()(c)
+
+	assert.EqualValues(t, 16, cycles, "cycles")
+	assert.Equal(t, uint16(0x2211), c.regs.PC, "PC")
+	assert.True(t, c.ime, "IME")
+}
+
+func TestInstructions_call_conditional(t *testing.T) {
+	mem := make(simpleRAM, 0xFFFF)
+	c := &CPU{mem: mem}
+	c.regs.PC = 0x1122
+	c.regs.SP = 0xAA02
+	mem[0x1122] = 0x44
+	mem[0x1123] = 0x55
+
+	cycles := call(flagC, true)(c)
+
+	// C is not set, so the call is not taken but the args are still consumed.
+	assert.EqualValues(t, 12, cycles, "cycles")
+	assert.EqualValues(t, 0x1124, c.regs.PC, "PC")
+	assert.EqualValues(t, 0xAA02, c.regs.SP, "SP")
+}
+
+func TestInstructions_rst(t *testing.T) {
+	mem := make(simpleRAM, 0xFFFF)
+	c := &CPU{mem: mem}
+	c.regs.PC = 0x1122
+	c.regs.SP = 0xAA02
+
+	cycles := rst(0x38)(c)
+
+	assert.EqualValues(t, 16, cycles, "cycles")
+	assert.EqualValues(t, 0x0038, c.regs.PC, "PC")
+	assert.EqualValues(t, 0xAA00, c.regs.SP, "SP")
+	assert.EqualValues(t, 0x22, mem[0xAA00], "Stack - low nibble")
+	assert.EqualValues(t, 0x11, mem[0xAA01], "Stack - high nibble")
+}
+
+func TestInstructions_jp(t *testing.T) {
+	tests := []struct {
+		name      string
+		flag      func(*CPU) bool
+		condition bool
+		cycles    uint8
+		pc        uint16
+	}{
+		{"unconditional jump", nil, true, 16, 0x5544},
+		{"conditional jump taken", flagZ, true, 16, 0x5544},
+		{"conditional jump not taken", flagZ, false, 12, 0x1124},
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			mem := make(simpleRAM, 0xFFFF)
+			c := &CPU{mem: mem, flags: flags{Z: true}}
+			c.regs.PC = 0x1122
+			mem[0x1122] = 0x44
+			mem[0x1123] = 0x55
+
+			cycles := jp(tC.flag, tC.condition)(c)
+
+			assert.Equal(t, tC.cycles, cycles, "cycles")
+			assert.Equal(t, tC.pc, c.regs.PC, "PC")
+		})
+	}
+}
+
+func TestInstructions_jp16(t *testing.T) {
+	c := &CPU{}
+	c.regs.H, c.regs.L = 0x11, 0x22
+
+	cycles := jp16(regHL)(c)
+
+	assert.EqualValues(t, 4, cycles, "cycles")
+	assert.EqualValues(t, 0x1122, c.regs.PC, "PC")
+}
+
 func TestInstructions_bit8(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -647,10 +1019,89 @@ func TestInstructions_nop(t *testing.T) {
 }
 
 func TestInstructions_stop(t *testing.T) {
-	c := &CPU{mem: make(simpleRAM, 0xFFFF)}
+	mem := make(simpleRAM, 0xFFFF)
+	mem[0xFF04] = 0xAB
+	c := &CPU{mem: mem}
 	cycles := stop()(c)
 	assert.EqualValues(t, 4, cycles, "cycles")
 	assert.EqualValues(t, 0x01, c.regs.PC, "PC") // stop has one ignored arg.
+	assert.EqualValues(t, 0x00, mem[0xFF04], "DIV")
+	assert.True(t, c.stopped, "stopped")
+}
+
+func TestInstructions_stop_speedSwitch(t *testing.T) {
+	mem := make(simpleRAM, 0xFFFF)
+	c := New(model.CGB, mem, interrupts.New())
+
+	// Without arming the switch, STOP stops the CPU.
+	stop()(c)
+	assert.True(t, c.stopped, "stopped")
+	assert.False(t, c.DoubleSpeed(), "double speed")
+
+	c.stopped = false
+	mem[0xFF4D] = 0x01
+	stop()(c)
+	assert.False(t, c.stopped, "stopped")
+	assert.True(t, c.DoubleSpeed(), "double speed")
+	assert.EqualValues(t, 0x80, mem[0xFF4D], "KEY1")
+
+	// The CPU pauses during the switch, then runs the NOP that follows.
+	pc := c.regs.PC
+	for i := 0; i < speedSwitchCycles; i++ {
+		c.Tick()
+	}
+	assert.Equal(t, pc, c.regs.PC, "paused")
+	c.Tick()
+	assert.Equal(t, pc+1, c.regs.PC, "running")
+
+	mem[0xFF4D] |= 0x01
+	stop()(c)
+	assert.False(t, c.DoubleSpeed(), "double speed")
+	assert.EqualValues(t, 0x00, mem[0xFF4D], "KEY1")
+}
+
+func TestInstructions_halt(t *testing.T) {
+	irq := interrupts.New()
+	c := &CPU{irq: irq}
+	cycles := halt()(c)
+	assert.EqualValues(t, 4, cycles, "cycles")
+	assert.True(t, c.halted, "halted")
+	assert.False(t, c.haltBug, "HALT bug")
+
+	// With IME reset and an interrupt pending, the CPU doesn't halt.
+	c.halted = false
+	irq.Write(0xFFFF, 0xFF)
+	irq.Request(interrupts.VBlank)
+	halt()(c)
+	assert.False(t, c.halted, "halted")
+	assert.True(t, c.haltBug, "HALT bug")
+}
+
+func TestInstructions_halt_afterEI(t *testing.T) {
+	mem := make(simpleRAM, 0xFFFF)
+	irq := interrupts.New()
+	c := New(model.DMG, mem, irq)
+	c.regs.PC = 0x0100
+	c.regs.SP = 0xD000
+	irq.Write(0xFFFF, 0xFF)
+	irq.Request(interrupts.VBlank)
+	mem[0x0100] = 0xFB // EI
+	mem[0x0101] = 0x76 // HALT
+	mem[0x0040] = 0x04 // INC B
+	mem[0x0041] = 0x00 // NOP
+	mem[0x0042] = 0xD9 // RETI
+
+	// EI, HALT, the dispatch, INC B and NOP.
+	tick(c, 4+4+20+4+4)
+	assert.Equal(t, uint16(0x0042), c.regs.PC, "PC")
+	assert.Equal(t, uint8(0x01), c.regs.B, "B")
+	assert.Equal(t, uint8(0x01), mem[0xCFFE], "Stack - low nibble")
+	assert.Equal(t, uint8(0x01), mem[0xCFFF], "Stack - high nibble")
+
+	// The handler returns to HALT, which now halts the CPU.
+	tick(c, 16+4)
+	assert.Equal(t, uint16(0x0102), c.regs.PC, "PC")
+	assert.True(t, c.halted, "halted")
 }
 
 func TestInstructions_ld16ConstRefSP(t *testing.T) {
@@ -674,6 +1125,7 @@ func TestInstructions_rlc8(t *testing.T) {
 		res   uint8
 		flags flags
 	}{
+		{"result is zero", 0x00, 0x00, flags{Z: true}},
 		{"no carry", 0b00000011, 0b00000110, flags{}},
 		{"with carry", 0b11000000, 0b10000001, flags{C: true}},
 	}
@@ -685,7 +1137,7 @@ func TestInstructions_rlc8(t *testing.T) {
 
 			cycles := rlc8(regA)(c)
 
-			assert.EqualValues(t, 4, cycles, "cycles")
+			assert.EqualValues(t, 8, cycles, "cycles")
 			assert.Equal(t, tC.res, c.regs.A, "A")
 			assert.Equal(t, tC.flags, c.flags, "flags")
 		})
@@ -699,6 +1151,7 @@ func TestInstructions_rrc8(t *testing.T) {
 		res   uint8
 		flags flags
 	}{
+		{"result is zero", 0x00, 0x00, flags{Z: true}},
 		{"no carry", 0b00001100, 0b00000110, flags{}},
 		{"with carry", 0b00000011, 0b10000001, flags{C: true}},
 	}
@@ -710,13 +1163,88 @@ func TestInstructions_rrc8(t *testing.T) {
 
 			cycles := rrc8(regA)(c)
 
-			assert.EqualValues(t, 4, cycles, "cycles")
+			assert.EqualValues(t, 8, cycles, "cycles")
 			assert.Equal(t, tC.res, c.regs.A, "A")
 			assert.Equal(t, tC.flags, c.flags, "flags")
 		})
 	}
 }
 
+func TestInstructions_rotA(t *testing.T) {
+	c := &CPU{}
+	c.regs.A = 0x80
+
+	cycles := rotA(rl8(regA))(c)
+
+	// RL A would set Z but RLA always resets it.
+	assert.EqualValues(t, 4, cycles, "cycles")
+	assert.EqualValues(t, 0x00, c.regs.A, "A")
+	assert.Equal(t, flags{C: true}, c.flags, "flags")
+}
+
+func TestInstructions_shifts(t *testing.T) {
+	tests := []struct {
+		name  string
+		op    func(reg8) runnable
+		v     uint8
+		res   uint8
+		flags flags
+	}{
+		{"SLA", sla8, 0b10000001, 0b00000010, flags{C: true}},
+		{"SLA result is zero", sla8, 0b10000000, 0x00, flags{Z: true, C: true}},
+		{"SRA keeps bit 7", sra8, 0b10000001, 0b11000000, flags{C: true}},
+		{"SRL resets bit 7", srl8, 0b10000001, 0b01000000, flags{C: true}},
+		{"SRL result is zero", srl8, 0b00000001, 0x00, flags{Z: true, C: true}},
+		{"SWAP", swap8, 0xAB, 0xBA, flags{}},
+		{"SWAP result is zero", swap8, 0x00, 0x00, flags{Z: true}},
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			c := &CPU{}
+			c.flags = flags{false, true, true, false}
+			c.regs.B = tC.v
+
+			cycles := tC.op(regB)(c)
+
+			assert.EqualValues(t, 8, cycles, "cycles")
+			assert.Equal(t, tC.res, c.regs.B, "B")
+			assert.Equal(t, tC.flags, c.flags, "flags")
+		})
+	}
+}
+
+func TestInstructions_res8(t *testing.T) {
+	c := &CPU{}
+	c.regs.B = 0xFF
+
+	cycles := res8(3, regB)(c)
+
+	assert.EqualValues(t, 8, cycles, "cycles")
+	assert.EqualValues(t, 0b11110111, c.regs.B, "B")
+}
+
+func TestInstructions_set8(t *testing.T) {
+	c := &CPU{}
+	c.regs.B = 0x00
+
+	cycles := set8(3, regB)(c)
+
+	assert.EqualValues(t, 8, cycles, "cycles")
+	assert.EqualValues(t, 0b00001000, c.regs.B, "B")
+}
+
+func TestInstructions_refHL(t *testing.T) {
+	mem := make(simpleRAM, 0xFFFF)
+	c := &CPU{mem: mem}
+	c.regs.H, c.regs.L = 0x11, 0x22
+	mem[0x1122] = 0x01
+
+	cycles := withCycles(16, rl8(refHL))(c)
+
+	assert.EqualValues(t, 16, cycles, "cycles")
+	assert.EqualValues(t, 0x02, mem[0x1122], "(HL)")
+}
+
 func TestInstructions_cpl8(t *testing.T) {
 	c := &CPU{}
 	c.regs.C = 0b01010001
@@ -770,7 +1298,7 @@ func TestAllOpcodesDefined(t *testing.T) {
 	// https://www.pastraiser.com/cpu/gameboy/gameboy_opcodes.html
 
 	// Base instruction set.
-	invalid := []uint16{0xD3, 0xE3, 0xE4, 0xF4, 0xCB, 0xDB, 0xEB, 0xEC, 0xFC, 0xDD, 0xDE, 0xDF}
+	invalid := []uint16{0xCB, 0xD3, 0xDB, 0xDD, 0xE3, 0xE4, 0xEB, 0xEC, 0xED, 0xF4, 0xFC, 0xFD}
 	skip := func(opcode uint16) bool {
 		for _, c := range invalid {
 			if opcode == c {
@@ -783,16 +1311,12 @@ func TestAllOpcodesDefined(t *testing.T) {
 		if skip(opcode) {
 			continue
 		}
-		assert.NotNilf(t, gbcInstructions[opcode].run, "missing opcode 0x%04X", opcode)
+		assert.NotNilf(t, instructions[opcode].run, "missing opcode 0x%02X", opcode)
 	}
 
 	// Extended instruction set.
-	for opcode := uint16(0); opcode <= 0xFF; opcode++ {
-		if skip(opcode) {
-			continue
-		}
-		exCode := 0xCB00 | opcode
-		assert.NotNilf(t, gbcInstructions[exCode].run, "missing opcode 0x%04X", exCode)
+	for opcode := 0; opcode <= 0xFF; opcode++ {
+		assert.NotNilf(t, cbInstructions[opcode].run, "missing opcode 0xCB%02X", opcode)
 	}
 }
 
@@ -1,6 +1,8 @@
 package cpu
 
-var gbcInstructions = map[uint16]instruction{
+// instructions is the base instruction set, indexed by opcode.
+// Illegal opcodes and the 0xCB prefix have no entry.
+var instructions = [256]instruction{
 	// 0x
 	0x00: {"NOP", nop()},
 	0x01: {"LD BC,nn", ld16Const(regBC)},
@@ -9,7 +11,7 @@ var gbcInstructions = map[uint16]instruction{
 	0x04: {"INC B", inc8(regB)},
 	0x05: {"DEC B", dec8(regB)},
 	0x06: {"LD B,n", ld8Const(regB)},
-	0x07: {"RLCA", rlc8(regA)},
+	0x07: {"RLCA", rotA(rlc8(regA))},
 	0x08: {"LD (nn),SP", ld16ConstRefSP()},
 	0x09: {"ADD HL,BC", add16(regHL, regBC)},
 	0x0A: {"LD A,(BC)", ld816Ref(regA, regBC, 0)},
@@ -17,16 +19,16 @@ var gbcInstructions = map[uint16]instruction{
 	0x0C: {"INC C", inc8(regC)},
 	0x0D: {"DEC C", dec8(regC)},
 	0x0E: {"LD C,n", ld8Const(regC)},
-	0x0F: {"RRCA", rrc8(regA)},
+	0x0F: {"RRCA", rotA(rrc8(regA))},
 	// 1x
-	0x10: {"STOP", nop()}, // Stop updates the CPU state, nothing to do here.
+	0x10: {"STOP", stop()},
 	0x11: {"LD DE,nn", ld16Const(regDE)},
 	0x12: {"LD (DE),A", ld16Ref8(regDE, regA, 0)},
 	0x13: {"INC DE", inc16(regDE)},
 	0x14: {"INC D", inc8(regD)},
 	0x15: {"DEC D", dec8(regD)},
 	0x16: {"LD D,n", ld8Const(regD)},
-	0x17: {"RLA", rl8(regA)},
+	0x17: {"RLA", rotA(rl8(regA))},
 	0x18: {"JR n", jr(nil, true)},
 	0x19: {"ADD HL,DE", add16(regHL, regDE)},
 	0x1A: {"LD A,(DE)", ld816Ref(regA, regDE, 0)},
@@ -34,7 +36,7 @@ var gbcInstructions = map[uint16]instruction{
 	0x1C: {"INC E", inc8(regE)},
 	0x1D: {"DEC E", dec8(regE)},
 	0x1E: {"LD E,n", ld8Const(regE)},
-	0x1F: {"RRA", rr8(regA)},
+	0x1F: {"RRA", rotA(rr8(regA))},
 	// 2x
 	0x20: {"JR NZ,n", jr(flagZ, false)},
 	0x21: {"LD HL,nn", ld16Const(regHL)},
@@ -121,35 +123,421 @@ var gbcInstructions = map[uint16]instruction{
 	0x6E: {"LD L,(HL)", ld816Ref(regL, regHL, 0)},
 	0x6F: {"LD L,A", ld88(regL, regA)},
 	// 7X
+	0x70: {"LD (HL),B", ld16Ref8(regHL, regB, 0)},
+	0x71: {"LD (HL),C", ld16Ref8(regHL, regC, 0)},
+	0x72: {"LD (HL),D", ld16Ref8(regHL, regD, 0)},
+	0x73: {"LD (HL),E", ld16Ref8(regHL, regE, 0)},
+	0x74: {"LD (HL),H", ld16Ref8(regHL, regH, 0)},
+	0x75: {"LD (HL),L", ld16Ref8(regHL, regL, 0)},
+	0x76: {"HALT", halt()},
 	0x77: {"LD (HL),A", ld16Ref8(regHL, regA, 0)},
 	0x78: {"LD A,B", ld88(regA, regB)},
+	0x79: {"LD A,C", ld88(regA, regC)},
+	0x7A: {"LD A,D", ld88(regA, regD)},
 	0x7B: {"LD A,E", ld88(regA, regE)},
 	0x7C: {"LD A,H", ld88(regA, regH)},
 	0x7D: {"LD A,L", ld88(regA, regL)},
+	0x7E: {"LD A,(HL)", ld816Ref(regA, regHL, 0)},
+	0x7F: {"LD A,A", ld88(regA, regA)},
 	// 8X
-	0x86: {"ADD (HL)", add16Ref(regHL)},
+	0x80: {"ADD A,B", add8(regB)},
+	0x81: {"ADD A,C", add8(regC)},
+	0x82: {"ADD A,D", add8(regD)},
+	0x83: {"ADD A,E", add8(regE)},
+	0x84: {"ADD A,H", add8(regH)},
+	0x85: {"ADD A,L", add8(regL)},
+	0x86: {"ADD A,(HL)", add16Ref(regHL)},
+	0x87: {"ADD A,A", add8(regA)},
+	0x88: {"ADC A,B", adc8(regB)},
+	0x89: {"ADC A,C", adc8(regC)},
+	0x8A: {"ADC A,D", adc8(regD)},
+	0x8B: {"ADC A,E", adc8(regE)},
+	0x8C: {"ADC A,H", adc8(regH)},
+	0x8D: {"ADC A,L", adc8(regL)},
+	0x8E: {"ADC A,(HL)", adc16Ref(regHL)},
+	0x8F: {"ADC A,A", adc8(regA)},
 	// 9X
 	0x90: {"SUB B", sub8(regB)},
+	0x91: {"SUB C", sub8(regC)},
+	0x92: {"SUB D", sub8(regD)},
+	0x93: {"SUB E", sub8(regE)},
+	0x94: {"SUB H", sub8(regH)},
+	0x95: {"SUB L", sub8(regL)},
+	0x96: {"SUB (HL)", sub16Ref(regHL)},
+	0x97: {"SUB A", sub8(regA)},
+	0x98: {"SBC A,B", sbc8(regB)},
+	0x99: {"SBC A,C", sbc8(regC)},
+	0x9A: {"SBC A,D", sbc8(regD)},
+	0x9B: {"SBC A,E", sbc8(regE)},
+	0x9C: {"SBC A,H", sbc8(regH)},
+	0x9D: {"SBC A,L", sbc8(regL)},
+	0x9E: {"SBC A,(HL)", sbc16Ref(regHL)},
+	0x9F: {"SBC A,A", sbc8(regA)},
 	// AX
+	0xA0: {"AND B", and8(regB)},
+	0xA1: {"AND C", and8(regC)},
+	0xA2: {"AND D", and8(regD)},
+	0xA3: {"AND E", and8(regE)},
+	0xA4: {"AND H", and8(regH)},
+	0xA5: {"AND L", and8(regL)},
+	0xA6: {"AND (HL)", and16Ref(regHL)},
+	0xA7: {"AND A", and8(regA)},
+	0xA8: {"XOR B", xor8(regB)},
+	0xA9: {"XOR C", xor8(regC)},
+	0xAA: {"XOR D", xor8(regD)},
+	0xAB: {"XOR E", xor8(regE)},
+	0xAC: {"XOR H", xor8(regH)},
+	0xAD: {"XOR L", xor8(regL)},
+	0xAE: {"XOR (HL)", xor16Ref(regHL)},
 	0xAF: {"XOR A", xor8(regA)},
 	// BX
+	0xB0: {"OR B", or8(regB)},
+	0xB1: {"OR C", or8(regC)},
+	0xB2: {"OR D", or8(regD)},
+	0xB3: {"OR E", or8(regE)},
+	0xB4: {"OR H", or8(regH)},
+	0xB5: {"OR L", or8(regL)},
+	0xB6: {"OR (HL)", or16Ref(regHL)},
+	0xB7: {"OR A", or8(regA)},
+	0xB8: {"CP B", cp8(regB)},
+	0xB9: {"CP C", cp8(regC)},
+	0xBA: {"CP D", cp8(regD)},
+	0xBB: {"CP E", cp8(regE)},
+	0xBC: {"CP H", cp8(regH)},
+	0xBD: {"CP L", cp8(regL)},
 	0xBE: {"CP (HL)", cp16Ref(regHL)},
+	0xBF: {"CP A", cp8(regA)},
 	// CX
+	0xC0: {"RET NZ", ret(flagZ, false)},
 	0xC1: {"POP BC", pop16(regBC)},
+	0xC2: {"JP NZ,nn", jp(flagZ, false)},
+	0xC3: {"JP nn", jp(nil, true)},
+	0xC4: {"CALL NZ,nn", call(flagZ, false)},
 	0xC5: {"PUSH BC", push16(regBC)},
-	0xC9: {"RET", ret()},
-	0xCD: {"CALL nn", call()},
+	0xC6: {"ADD A,n", addConst()},
+	0xC7: {"RST 00H", rst(0x00)},
+	0xC8: {"RET Z", ret(flagZ, true)},
+	0xC9: {"RET", ret(nil, true)},
+	0xCA: {"JP Z,nn", jp(flagZ, true)},
+	0xCC: {"CALL Z,nn", call(flagZ, true)},
+	0xCD: {"CALL nn", call(nil, true)},
+	0xCE: {"ADC A,n", adcConst()},
+	0xCF: {"RST 08H", rst(0x08)},
 	// DX
+	0xD0: {"RET NC", ret(flagC, false)},
+	0xD1: {"POP DE", pop16(regDE)},
+	0xD2: {"JP NC,nn", jp(flagC, false)},
+	0xD4: {"CALL NC,nn", call(flagC, false)},
+	0xD5: {"PUSH DE", push16(regDE)},
+	0xD6: {"SUB n", subConst()},
+	0xD7: {"RST 10H", rst(0x10)},
+	0xD8: {"RET C", ret(flagC, true)},
+	0xD9: {"RETI", reti()},
+	0xDA: {"JP C,nn", jp(flagC, true)},
+	0xDC: {"CALL C,nn", call(flagC, true)},
+	0xDE: {"SBC A,n", sbcConst()},
+	0xDF: {"RST 18H", rst(0x18)},
 	// EX
 	0xE0: {"LD (n),A", ld8ConstRef8(regA)},
+	0xE1: {"POP HL", pop16(regHL)},
 	0xE2: {"LD (C),A", ld8Ref8(regC, regA)},
+	0xE5: {"PUSH HL", push16(regHL)},
+	0xE6: {"AND n", andConst()},
+	0xE7: {"RST 20H", rst(0x20)},
+	0xE8: {"ADD SP,n", addSPConst()},
+	0xE9: {"JP (HL)", jp16(regHL)},
 	0xEA: {"LD (nn),A", ld16ConstRef8(regA)},
-	0xF0: {"LD A,(n)", ld88ConstRef(regA)},
+	0xEE: {"XOR n", xorConst()},
+	0xEF: {"RST 28H", rst(0x28)},
 	// FX
+	0xF0: {"LD A,(n)", ld88ConstRef(regA)},
+	0xF1: {"POP AF", pop16(regAF)},
+	0xF2: {"LD A,(C)", ld88Ref(regA, regC)},
+	0xF3: {"DI", di()},
+	0xF5: {"PUSH AF", push16(regAF)},
+	0xF6: {"OR n", orConst()},
+	0xF7: {"RST 30H", rst(0x30)},
+	0xF8: {"LD HL,SP+n", ld16SPOffset(regHL)},
+	0xF9: {"LD SP,HL", ld1616(regSP, regHL)},
+	0xFA: {"LD A,(nn)", ld816ConstRef(regA)},
+	0xFB: {"EI", ei()},
 	0xFE: {"CP n", cpConst()},
+	0xFF: {"RST 38H", rst(0x38)},
+}
 
-	// Extended set.
-	0xCB11: {"RL C", rl8(regC)},
-	0xCB17: {"RL A", rl8(regA)},
-	0xCB7C: {"BIT 7,H", bit8(7, regH)},
+// cbInstructions is the extended instruction set,
+// indexed by the opcode following the 0xCB prefix.
+var cbInstructions = [256]instruction{
+	// CB0X
+	0x00: {"RLC B", rlc8(regB)},
+	0x01: {"RLC C", rlc8(regC)},
+	0x02: {"RLC D", rlc8(regD)},
+	0x03: {"RLC E", rlc8(regE)},
+	0x04: {"RLC H", rlc8(regH)},
+	0x05: {"RLC L", rlc8(regL)},
+	0x06: {"RLC (HL)", withCycles(16, rlc8(refHL))},
+	0x07: {"RLC A", rlc8(regA)},
+	0x08: {"RRC B", rrc8(regB)},
+	0x09: {"RRC C", rrc8(regC)},
+	0x0A: {"RRC D", rrc8(regD)},
+	0x0B: {"RRC E", rrc8(regE)},
+	0x0C: {"RRC H", rrc8(regH)},
+	0x0D: {"RRC L", rrc8(regL)},
+	0x0E: {"RRC (HL)", withCycles(16, rrc8(refHL))},
+	0x0F: {"RRC A", rrc8(regA)},
+	// CB1X
+	0x10: {"RL B", rl8(regB)},
+	0x11: {"RL C", rl8(regC)},
+	0x12: {"RL D", rl8(regD)},
+	0x13: {"RL E", rl8(regE)},
+	0x14: {"RL H", rl8(regH)},
+	0x15: {"RL L", rl8(regL)},
+	0x16: {"RL (HL)", withCycles(16, rl8(refHL))},
+	0x17: {"RL A", rl8(regA)},
+	0x18: {"RR B", rr8(regB)},
+	0x19: {"RR C", rr8(regC)},
+	0x1A: {"RR D", rr8(regD)},
+	0x1B: {"RR E", rr8(regE)},
+	0x1C: {"RR H", rr8(regH)},
+	0x1D: {"RR L", rr8(regL)},
+	0x1E: {"RR (HL)", withCycles(16, rr8(refHL))},
+	0x1F: {"RR A", rr8(regA)},
+	// CB2X
+	0x20: {"SLA B", sla8(regB)},
+	0x21: {"SLA C", sla8(regC)},
+	0x22: {"SLA D", sla8(regD)},
+	0x23: {"SLA E", sla8(regE)},
+	0x24: {"SLA H", sla8(regH)},
+	0x25: {"SLA L", sla8(regL)},
+	0x26: {"SLA (HL)", withCycles(16, sla8(refHL))},
+	0x27: {"SLA A", sla8(regA)},
+	0x28: {"SRA B", sra8(regB)},
+	0x29: {"SRA C", sra8(regC)},
+	0x2A: {"SRA D", sra8(regD)},
+	0x2B: {"SRA E", sra8(regE)},
+	0x2C: {"SRA H", sra8(regH)},
+	0x2D: {"SRA L", sra8(regL)},
+	0x2E: {"SRA (HL)", withCycles(16, sra8(refHL))},
+	0x2F: {"SRA A", sra8(regA)},
+	// CB3X
+	0x30: {"SWAP B", swap8(regB)},
+	0x31: {"SWAP C", swap8(regC)},
+	0x32: {"SWAP D", swap8(regD)},
+	0x33: {"SWAP E", swap8(regE)},
+	0x34: {"SWAP H", swap8(regH)},
+	0x35: {"SWAP L", swap8(regL)},
+	0x36: {"SWAP (HL)", withCycles(16, swap8(refHL))},
+	0x37: {"SWAP A", swap8(regA)},
+	0x38: {"SRL B", srl8(regB)},
+	0x39: {"SRL C", srl8(regC)},
+	0x3A: {"SRL D", srl8(regD)},
+	0x3B: {"SRL E", srl8(regE)},
+	0x3C: {"SRL H", srl8(regH)},
+	0x3D: {"SRL L", srl8(regL)},
+	0x3E: {"SRL (HL)", withCycles(16, srl8(refHL))},
+	0x3F: {"SRL A", srl8(regA)},
+	// CB4X
+	0x40: {"BIT 0,B", bit8(0, regB)},
+	0x41: {"BIT 0,C", bit8(0, regC)},
+	0x42: {"BIT 0,D", bit8(0, regD)},
+	0x43: {"BIT 0,E", bit8(0, regE)},
+	0x44: {"BIT 0,H", bit8(0, regH)},
+	0x45: {"BIT 0,L", bit8(0, regL)},
+	0x46: {"BIT 0,(HL)", withCycles(12, bit8(0, refHL))},
+	0x47: {"BIT 0,A", bit8(0, regA)},
+	0x48: {"BIT 1,B", bit8(1, regB)},
+	0x49: {"BIT 1,C", bit8(1, regC)},
+	0x4A: {"BIT 1,D", bit8(1, regD)},
+	0x4B: {"BIT 1,E", bit8(1, regE)},
+	0x4C: {"BIT 1,H", bit8(1, regH)},
+	0x4D: {"BIT 1,L", bit8(1, regL)},
+	0x4E: {"BIT 1,(HL)", withCycles(12, bit8(1, refHL))},
+	0x4F: {"BIT 1,A", bit8(1, regA)},
+	// CB5X
+	0x50: {"BIT 2,B", bit8(2, regB)},
+	0x51: {"BIT 2,C", bit8(2, regC)},
+	0x52: {"BIT 2,D", bit8(2, regD)},
+	0x53: {"BIT 2,E", bit8(2, regE)},
+	0x54: {"BIT 2,H", bit8(2, regH)},
+	0x55: {"BIT 2,L", bit8(2, regL)},
+	0x56: {"BIT 2,(HL)", withCycles(12, bit8(2, refHL))},
+	0x57: {"BIT 2,A", bit8(2, regA)},
+	0x58: {"BIT 3,B", bit8(3, regB)},
+	0x59: {"BIT 3,C", bit8(3, regC)},
+	0x5A: {"BIT 3,D", bit8(3, regD)},
+	0x5B: {"BIT 3,E", bit8(3, regE)},
+	0x5C: {"BIT 3,H", bit8(3, regH)},
+	0x5D: {"BIT 3,L", bit8(3, regL)},
+	0x5E: {"BIT 3,(HL)", withCycles(12, bit8(3, refHL))},
+	0x5F: {"BIT 3,A", bit8(3, regA)},
+	// CB6X
+	0x60: {"BIT 4,B", bit8(4, regB)},
+	0x61: {"BIT 4,C", bit8(4, regC)},
+	0x62: {"BIT 4,D", bit8(4, regD)},
+	0x63: {"BIT 4,E", bit8(4, regE)},
+	0x64: {"BIT 4,H", bit8(4, regH)},
+	0x65: {"BIT 4,L", bit8(4, regL)},
+	0x66: {"BIT 4,(HL)", withCycles(12, bit8(4, refHL))},
+	0x67: {"BIT 4,A", bit8(4, regA)},
+	0x68: {"BIT 5,B", bit8(5, regB)},
+	0x69: {"BIT 5,C", bit8(5, regC)},
+	0x6A: {"BIT 5,D", bit8(5, regD)},
+	0x6B: {"BIT 5,E", bit8(5, regE)},
+	0x6C: {"BIT 5,H", bit8(5, regH)},
+	0x6D: {"BIT 5,L", bit8(5, regL)},
+	0x6E: {"BIT 5,(HL)", withCycles(12, bit8(5, refHL))},
+	0x6F: {"BIT 5,A", bit8(5, regA)},
+	// CB7X
+	0x70: {"BIT 6,B", bit8(6, regB)},
+	0x71: {"BIT 6,C", bit8(6, regC)},
+	0x72: {"BIT 6,D", bit8(6, regD)},
+	0x73: {"BIT 6,E", bit8(6, regE)},
+	0x74: {"BIT 6,H", bit8(6, regH)},
+	0x75: {"BIT 6,L", bit8(6, regL)},
+	0x76: {"BIT 6,(HL)", withCycles(12, bit8(6, refHL))},
+	0x77: {"BIT 6,A", bit8(6, regA)},
+	0x78: {"BIT 7,B", bit8(7, regB)},
+	0x79: {"BIT 7,C", bit8(7, regC)},
+	0x7A: {"BIT 7,D", bit8(7, regD)},
+	0x7B: {"BIT 7,E", bit8(7, regE)},
+	0x7C: {"BIT 7,H", bit8(7, regH)},
+	0x7D: {"BIT 7,L", bit8(7, regL)},
+	0x7E: {"BIT 7,(HL)", withCycles(12, bit8(7, refHL))},
+	0x7F: {"BIT 7,A", bit8(7, regA)},
+	// CB8X
+	0x80: {"RES 0,B", res8(0, regB)},
+	0x81: {"RES 0,C", res8(0, regC)},
+	0x82: {"RES 0,D", res8(0, regD)},
+	0x83: {"RES 0,E", res8(0, regE)},
+	0x84: {"RES 0,H", res8(0, regH)},
+	0x85: {"RES 0,L", res8(0, regL)},
+	0x86: {"RES 0,(HL)", withCycles(16, res8(0, refHL))},
+	0x87: {"RES 0,A", res8(0, regA)},
+	0x88: {"RES 1,B", res8(1, regB)},
+	0x89: {"RES 1,C", res8(1, regC)},
+	0x8A: {"RES 1,D", res8(1, regD)},
+	0x8B: {"RES 1,E", res8(1, regE)},
+	0x8C: {"RES 1,H", res8(1, regH)},
+	0x8D: {"RES 1,L", res8(1, regL)},
+	0x8E: {"RES 1,(HL)", withCycles(16, res8(1, refHL))},
+	0x8F: {"RES 1,A", res8(1, regA)},
+	// CB9X
+	0x90: {"RES 2,B", res8(2, regB)},
+	0x91: {"RES 2,C", res8(2, regC)},
+	0x92: {"RES 2,D", res8(2, regD)},
+	0x93: {"RES 2,E", res8(2, regE)},
+	0x94: {"RES 2,H", res8(2, regH)},
+	0x95: {"RES 2,L", res8(2, regL)},
+	0x96: {"RES 2,(HL)", withCycles(16, res8(2, refHL))},
+	0x97: {"RES 2,A", res8(2, regA)},
+	0x98: {"RES 3,B", res8(3, regB)},
+	0x99: {"RES 3,C", res8(3, regC)},
+	0x9A: {"RES 3,D", res8(3, regD)},
+	0x9B: {"RES 3,E", res8(3, regE)},
+	0x9C: {"RES 3,H", res8(3, regH)},
+	0x9D: {"RES 3,L", res8(3, regL)},
+	0x9E: {"RES 3,(HL)", withCycles(16, res8(3, refHL))},
+	0x9F: {"RES 3,A", res8(3, regA)},
+	// CBAX
+	0xA0: {"RES 4,B", res8(4, regB)},
+	0xA1: {"RES 4,C", res8(4, regC)},
+	0xA2: {"RES 4,D", res8(4, regD)},
+	0xA3: {"RES 4,E", res8(4, regE)},
+	0xA4: {"RES 4,H", res8(4, regH)},
+	0xA5: {"RES 4,L", res8(4, regL)},
+	0xA6: {"RES 4,(HL)", withCycles(16, res8(4, refHL))},
+	0xA7: {"RES 4,A", res8(4, regA)},
+	0xA8: {"RES 5,B", res8(5, regB)},
+	0xA9: {"RES 5,C", res8(5, regC)},
+	0xAA: {"RES 5,D", res8(5, regD)},
+	0xAB: {"RES 5,E", res8(5, regE)},
+	0xAC: {"RES 5,H", res8(5, regH)},
+	0xAD: {"RES 5,L", res8(5, regL)},
+	0xAE: {"RES 5,(HL)", withCycles(16, res8(5, refHL))},
+	0xAF: {"RES 5,A", res8(5, regA)},
+	// CBBX
+	0xB0: {"RES 6,B", res8(6, regB)},
+	0xB1: {"RES 6,C", res8(6, regC)},
+	0xB2: {"RES 6,D", res8(6, regD)},
+	0xB3: {"RES 6,E", res8(6, regE)},
+	0xB4: {"RES 6,H", res8(6, regH)},
+	0xB5: {"RES 6,L", res8(6, regL)},
+	0xB6: {"RES 6,(HL)", withCycles(16, res8(6, refHL))},
+	0xB7: {"RES 6,A", res8(6, regA)},
+	0xB8: {"RES 7,B", res8(7, regB)},
+	0xB9: {"RES 7,C", res8(7, regC)},
+	0xBA: {"RES 7,D", res8(7, regD)},
+	0xBB: {"RES 7,E", res8(7, regE)},
+	0xBC: {"RES 7,H", res8(7, regH)},
+	0xBD: {"RES 7,L", res8(7, regL)},
+	0xBE: {"RES 7,(HL)", withCycles(16, res8(7, refHL))},
+	0xBF: {"RES 7,A", res8(7, regA)},
+	// CBCX
+	0xC0: {"SET 0,B", set8(0, regB)},
+	0xC1: {"SET 0,C", set8(0, regC)},
+	0xC2: {"SET 0,D", set8(0, regD)},
+	0xC3: {"SET 0,E", set8(0, regE)},
+	0xC4: {"SET 0,H", set8(0, regH)},
+	0xC5: {"SET 0,L", set8(0, regL)},
+	0xC6: {"SET 0,(HL)", withCycles(16, set8(0, refHL))},
+	0xC7: {"SET 0,A", set8(0, regA)},
+	0xC8: {"SET 1,B", set8(1, regB)},
+	0xC9: {"SET 1,C", set8(1, regC)},
+	0xCA: {"SET 1,D", set8(1, regD)},
+	0xCB: {"SET 1,E", set8(1, regE)},
+	0xCC: {"SET 1,H", set8(1, regH)},
+	0xCD: {"SET 1,L", set8(1, regL)},
+	0xCE: {"SET 1,(HL)", withCycles(16, set8(1, refHL))},
+	0xCF: {"SET 1,A", set8(1, regA)},
+	// CBDX
+	0xD0: {"SET 2,B", set8(2, regB)},
+	0xD1: {"SET 2,C", set8(2, regC)},
+	0xD2: {"SET 2,D", set8(2, regD)},
+	0xD3: {"SET 2,E", set8(2, regE)},
+	0xD4: {"SET 2,H", set8(2, regH)},
+	0xD5: {"SET 2,L", set8(2, regL)},
+	0xD6: {"SET 2,(HL)", withCycles(16, set8(2, refHL))},
+	0xD7: {"SET 2,A", set8(2, regA)},
+	0xD8: {"SET 3,B", set8(3, regB)},
+	0xD9: {"SET 3,C", set8(3, regC)},
+	0xDA: {"SET 3,D", set8(3, regD)},
+	0xDB: {"SET 3,E", set8(3, regE)},
+	0xDC: {"SET 3,H", set8(3, regH)},
+	0xDD: {"SET 3,L", set8(3, regL)},
+	0xDE: {"SET 3,(HL)", withCycles(16, set8(3, refHL))},
+	0xDF: {"SET 3,A", set8(3, regA)},
+	// CBEX
+	0xE0: {"SET 4,B", set8(4, regB)},
+	0xE1: {"SET 4,C", set8(4, regC)},
+	0xE2: {"SET 4,D", set8(4, regD)},
+	0xE3: {"SET 4,E", set8(4, regE)},
+	0xE4: {"SET 4,H", set8(4, regH)},
+	0xE5: {"SET 4,L", set8(4, regL)},
+	0xE6: {"SET 4,(HL)", withCycles(16, set8(4, refHL))},
+	0xE7: {"SET 4,A", set8(4, regA)},
+	0xE8: {"SET 5,B", set8(5, regB)},
+	0xE9: {"SET 5,C", set8(5, regC)},
+	0xEA: {"SET 5,D", set8(5, regD)},
+	0xEB: {"SET 5,E", set8(5, regE)},
+	0xEC: {"SET 5,H", set8(5, regH)},
+	0xED: {"SET 5,L", set8(5, regL)},
+	0xEE: {"SET 5,(HL)", withCycles(16, set8(5, refHL))},
+	0xEF: {"SET 5,A", set8(5, regA)},
+	// CBFX
+	0xF0: {"SET 6,B", set8(6, regB)},
+	0xF1: {"SET 6,C", set8(6, regC)},
+	0xF2: {"SET 6,D", set8(6, regD)},
+	0xF3: {"SET 6,E", set8(6, regE)},
+	0xF4: {"SET 6,H", set8(6, regH)},
+	0xF5: {"SET 6,L", set8(6, regL)},
+	0xF6: {"SET 6,(HL)", withCycles(16, set8(6, refHL))},
+	0xF7: {"SET 6,A", set8(6, regA)},
+	0xF8: {"SET 7,B", set8(7, regB)},
+	0xF9: {"SET 7,C", set8(7, regC)},
+	0xFA: {"SET 7,D", set8(7, regD)},
+	0xFB: {"SET 7,E", set8(7, regE)},
+	0xFC: {"SET 7,H", set8(7, regH)},
+	0xFD: {"SET 7,L", set8(7, regL)},
+	0xFE: {"SET 7,(HL)", withCycles(16, set8(7, refHL))},
+	0xFF: {"SET 7,A", set8(7, regA)},
 }
@@ -1,78 +1,114 @@
 package cpu
 
-type setter8 func(v uint8)
-type getter8 func() uint8
-type reg8 func(c *CPU) (setter8, getter8)
-
-func access8(r *uint8) (setter8, getter8) {
-	set := func(v uint8) { *r = v }
-	get := func() uint8 { return *r }
-	return set, get
-}
-
-type setter16 func(v uint16)
-type getter16 func() uint16
-type reg16 func(c *CPU) (setter16, getter16)
-
-func access16Pair(high *uint8, low *uint8) (setter16, getter16) {
-	set := func(v uint16) {
-		*low = uint8(v & 0xFF)
-		*high = uint8((v >> 8) & 0xFF)
+// reg8 is an 8-bit register, or the byte in memory pointed by HL,
+// which instructions access through get and set.
+// Using values rather than closures keeps instructions allocation free.
+type reg8 uint8
+
+// All 8-bit registers.
+const (
+	regA reg8 = iota
+	regB
+	regC
+	regD
+	regE
+	regH
+	regL
+	// refHL accesses the byte in memory pointed by HL
+	// as if it was an 8-bit register, as in 'RL (HL)'.
+	refHL
+)
+
+func (r reg8) get(c *CPU) uint8 {
+	switch r {
+	case regA:
+		return c.regs.A
+	case regB:
+		return c.regs.B
+	case regC:
+		return c.regs.C
+	case regD:
+		return c.regs.D
+	case regE:
+		return c.regs.E
+	case regH:
+		return c.regs.H
+	case regL:
+		return c.regs.L
+	default:
+		return c.mem.Read(regHL.get(c))
 	}
-	get := func() uint16 {
-		return uint16(*low) | uint16(*high)<<8
-	}
-	return set, get
-}
-
-func access16(r *uint16) (setter16, getter16) {
-	set := func(v uint16) { *r = v }
-	get := func() uint16 { return *r }
-	return set, get
-}
-
-func regA(c *CPU) (setter8, getter8) {
-	return access8(&c.regs.A)
-}
-
-func regB(c *CPU) (setter8, getter8) {
-	return access8(&c.regs.B)
-}
-
-func regC(c *CPU) (setter8, getter8) {
-	return access8(&c.regs.C)
 }
 
-func regD(c *CPU) (setter8, getter8) {
-	return access8(&c.regs.D)
-}
-
-func regH(c *CPU) (setter8, getter8) {
-	return access8(&c.regs.H)
-}
-
-func regL(c *CPU) (setter8, getter8) {
-	return access8(&c.regs.L)
-}
-
-func regE(c *CPU) (setter8, getter8) {
-	return access8(&c.regs.E)
-}
-
-func regDE(c *CPU) (setter16, getter16) {
-	return access16Pair(&c.regs.D, &c.regs.E)
-}
-
-func regHL(c *CPU) (setter16, getter16) {
-	return access16Pair(&c.regs.H, &c.regs.L)
+func (r reg8) set(c *CPU, v uint8) {
+	switch r {
+	case regA:
+		c.regs.A = v
+	case regB:
+		c.regs.B = v
+	case regC:
+		c.regs.C = v
+	case regD:
+		c.regs.D = v
+	case regE:
+		c.regs.E = v
+	case regH:
+		c.regs.H = v
+	case regL:
+		c.regs.L = v
+	default:
+		c.mem.Write(regHL.get(c), v)
+	}
 }
 
-func regBC(c *CPU) (setter16, getter16) {
-	return access16Pair(&c.regs.B, &c.regs.C)
+// reg16 is a 16-bit register, which instructions access through get and set.
+type reg16 uint8
+
+// All 16-bit registers.
+const (
+	// regAF accesses A and F, where F holds the flags
+	// in its high nibble and always has the low nibble set to zero.
+	regAF reg16 = iota
+	regBC
+	regDE
+	regHL
+	regSP
+)
+
+func pair(high, low uint8) uint16 {
+	return uint16(high)<<8 | uint16(low)
+}
+
+func (r reg16) get(c *CPU) uint16 {
+	switch r {
+	case regAF:
+		return pair(c.regs.A, c.flags.byte())
+	case regBC:
+		return pair(c.regs.B, c.regs.C)
+	case regDE:
+		return pair(c.regs.D, c.regs.E)
+	case regHL:
+		return pair(c.regs.H, c.regs.L)
+	default:
+		return c.regs.SP
+	}
 }
 
-func regSP(c *CPU) (setter16, getter16) {
-	return access16(&c.regs.SP)
+func (r reg16) set(c *CPU, v uint16) {
+	high, low := uint8(v>>8), uint8(v)
+	switch r {
+	case regAF:
+		c.regs.A = high
+		c.flags.setByte(low)
+	case regBC:
+		c.regs.B, c.regs.C = high, low
+	case regDE:
+		c.regs.D, c.regs.E = high, low
+	case regHL:
+		c.regs.H, c.regs.L = high, low
+	default:
+		c.regs.SP = v
+	}
 }
 
 func flagZ(c *CPU) bool {
@@ -0,0 +1,98 @@
+package interrupts
+
+// More info about interrupts can be found here:
+// https://gbdev.io/pandocs/#interrupts
+
+// Kind is a source of interrupts.
+// Its value is the bit used in the IE and IF registers
+// and lower values have higher priority.
+type Kind uint8
+
+// All interrupt kinds, from the highest to the lowest priority.
+const (
+	VBlank Kind = iota
+	LCDStat
+	Timer
+	Serial
+	Joypad
+)
+
+// Vector returns the address of the interrupt handler.
+func (k Kind) Vector() uint16 {
+	return 0x0040 + uint16(k)*8
+}
+
+const (
+	// IF - Interrupt Flag
+	// https://gbdev.io/pandocs/#ff0f-if-interrupt-flag-r-w
+	flagAddr = uint16(0xFF0F)
+	// IE - Interrupt Enable
+	// https://gbdev.io/pandocs/#ffff-ie-interrupt-enable-r-w
+	enableAddr = uint16(0xFFFF)
+
+	// Only the lowest 5 bits of IF are used, the others always read 1.
+	flagMask = uint8(0x1F)
+)
+
+// Controller keeps track of the requested and enabled interrupts.
+// It is an address space mapping the IF and IE registers.
+type Controller struct {
+	flag   uint8
+	enable uint8
+}
+
+// New creates a new interrupt controller.
+func New() *Controller {
+	return &Controller{}
+}
+
+// Contains returns true when the address is part of the address space.
+func (c *Controller) Contains(addr uint16) bool {
+	return addr == flagAddr || addr == enableAddr
+}
+
+// Read returns the byte at the given address.
+func (c *Controller) Read(addr uint16) uint8 {
+	if addr == flagAddr {
+		return c.flag | ^flagMask
+	}
+	return c.enable
+}
+
+// Write writes a value at the given address.
+func (c *Controller) Write(addr uint16, v uint8) {
+	if addr == flagAddr {
+		c.flag = v & flagMask
+		return
+	}
+	c.enable = v
+}
+
+// Request requests an interrupt of the given kind.
+// The CPU will service it as soon as it's enabled.
+func (c *Controller) Request(k Kind) {
+	c.flag |= 1 << k
+}
+
+// Clear clears a request, which happens when the CPU services it.
+func (c *Controller) Clear(k Kind) {
+	c.flag &^= 1 << k
+}
+
+// Requested returns true when an interrupt of the given kind
+// has been requested, whether it is enabled or not.
+func (c *Controller) Requested(k Kind) bool {
+	return c.flag&(1<<k) != 0
+}
+
+// Pending returns the interrupt with the highest priority
+// that is both requested and enabled, if any.
+func (c *Controller) Pending() (Kind, bool) {
+	active := c.flag & c.enable & flagMask
+	for k := VBlank; k <= Joypad; k++ {
+		if active&(1<<k) != 0 {
+			return k, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,56 @@
+package interrupts_test
+
+import (
+	"testing"
+
+	"github.com/andreaperizzato/gameboy/interrupts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestController_ReadWrite(t *testing.T) {
+	c := interrupts.New()
+	assert.True(t, c.Contains(0xFF0F), "IF")
+	assert.True(t, c.Contains(0xFFFF), "IE")
+	assert.False(t, c.Contains(0xFF10), "outside")
+
+	// Unused bits of IF always read 1.
+	c.Write(0xFF0F, 0xFF)
+	assert.Equal(t, uint8(0xFF), c.Read(0xFF0F))
+	c.Write(0xFF0F, 0x00)
+	assert.Equal(t, uint8(0xE0), c.Read(0xFF0F))
+
+	c.Write(0xFFFF, 0xAB)
+	assert.Equal(t, uint8(0xAB), c.Read(0xFFFF))
+}
+
+func TestController_Pending(t *testing.T) {
+	c := interrupts.New()
+	_, ok := c.Pending()
+	assert.False(t, ok, "nothing requested")
+
+	c.Request(interrupts.Timer)
+	c.Request(interrupts.Joypad)
+	assert.Equal(t, uint8(0xF4), c.Read(0xFF0F), "IF")
+	assert.True(t, c.Requested(interrupts.Timer), "timer requested")
+	assert.False(t, c.Requested(interrupts.Serial), "serial not requested")
+	_, ok = c.Pending()
+	assert.False(t, ok, "nothing enabled")
+
+	c.Write(0xFFFF, 0xFF)
+	k, ok := c.Pending()
+	assert.True(t, ok)
+	assert.Equal(t, interrupts.Timer, k, "highest priority first")
+
+	c.Clear(interrupts.Timer)
+	k, ok = c.Pending()
+	assert.True(t, ok)
+	assert.Equal(t, interrupts.Joypad, k)
+}
+
+func TestKind_Vector(t *testing.T) {
+	assert.Equal(t, uint16(0x40), interrupts.VBlank.Vector())
+	assert.Equal(t, uint16(0x48), interrupts.LCDStat.Vector())
+	assert.Equal(t, uint16(0x50), interrupts.Timer.Vector())
+	assert.Equal(t, uint16(0x58), interrupts.Serial.Vector())
+	assert.Equal(t, uint16(0x60), interrupts.Joypad.Vector())
+}
@@ -0,0 +1,29 @@
+package memory
+
+import (
+	"fmt"
+	"io"
+)
+
+// BatteryBacked is implemented by cartridges whose RAM is kept
+// powered by a battery when the console is off, and must be saved.
+type BatteryBacked interface {
+	// Save writes the content of the memory in the format
+	// used by other emulators, usually a plain RAM dump.
+	Save(w io.Writer) error
+	// Load restores the content of the memory written by Save.
+	Load(r io.Reader) error
+}
+
+func saveRAM(w io.Writer, ram []uint8) error {
+	_, err := w.Write(ram)
+	return err
+}
+
+func loadRAM(r io.Reader, ram []uint8) error {
+	n, err := io.ReadFull(r, ram)
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		return fmt.Errorf("save is too small: expected %d bytes of ram, got %d", len(ram), n)
+	}
+	return err
+}
@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/andreaperizzato/gameboy/model"
+)
+
+// More info about the boot ROMs can be found here:
+// https://gbdev.io/pandocs/#power-up-sequence
+// https://gbdev.gg8.se/wiki/articles/Gameboy_Bootstrap_ROM
+
+const (
+	// dmgBootSize is the size of the boot ROM of the DMG, MGB and SGB.
+	dmgBootSize = 0x100
+	// cgbBootSize is the size of the boot ROM of the CGB and AGB.
+	// The bytes at 0x0100-0x01FF are not mapped, so that
+	// the boot ROM can read the cartridge header.
+	cgbBootSize = 0x900
+	cgbHoleEnd  = uint16(0x01FF)
+)
+
+// ErrBootROMSize is returned when a boot ROM doesn't have the size of the model.
+var ErrBootROMSize = errors.New("invalid boot ROM size")
+
+var dmgBoot = []uint8{
+	0x31, 0xFE, 0xFF, 0xAF, 0x21, 0xFF, 0x9F, 0x32, 0xCB, 0x7C, 0x20, 0xFB, 0x21, 0x26, 0xFF, 0x0E,
+	0x11, 0x3E, 0x80, 0x32, 0xE2, 0x0C, 0x3E, 0xF3, 0xE2, 0x32, 0x3E, 0x77, 0x77, 0x3E, 0xFC, 0xE0,
+	0x47, 0x11, 0x04, 0x01, 0x21, 0x10, 0x80, 0x1A, 0xCD, 0x95, 0x00, 0xCD, 0x96, 0x00, 0x13, 0x7B,
+	0xFE, 0x34, 0x20, 0xF3, 0x11, 0xD8, 0x00, 0x06, 0x08, 0x1A, 0x13, 0x22, 0x23, 0x05, 0x20, 0xF9,
+	0x3E, 0x19, 0xEA, 0x10, 0x99, 0x21, 0x2F, 0x99, 0x0E, 0x0C, 0x3D, 0x28, 0x08, 0x32, 0x0D, 0x20,
+	0xF9, 0x2E, 0x0F, 0x18, 0xF3, 0x67, 0x3E, 0x64, 0x57, 0xE0, 0x42, 0x3E, 0x91, 0xE0, 0x40, 0x04,
+	0x1E, 0x02, 0x0E, 0x0C, 0xF0, 0x44, 0xFE, 0x90, 0x20, 0xFA, 0x0D, 0x20, 0xF7, 0x1D, 0x20, 0xF2,
+	0x0E, 0x13, 0x24, 0x7C, 0x1E, 0x83, 0xFE, 0x62, 0x28, 0x06, 0x1E, 0xC1, 0xFE, 0x64, 0x20, 0x06,
+	0x7B, 0xE2, 0x0C, 0x3E, 0x87, 0xE2, 0xF0, 0x42, 0x90, 0xE0, 0x42, 0x15, 0x20, 0xD2, 0x05, 0x20,
+	0x4F, 0x16, 0x20, 0x18, 0xCB, 0x4F, 0x06, 0x04, 0xC5, 0xCB, 0x11, 0x17, 0xC1, 0xCB, 0x11, 0x17,
+	0x05, 0x20, 0xF5, 0x22, 0x23, 0x22, 0x23, 0xC9, 0xCE, 0xED, 0x66, 0x66, 0xCC, 0x0D, 0x00, 0x0B,
+	0x03, 0x73, 0x00, 0x83, 0x00, 0x0C, 0x00, 0x0D, 0x00, 0x08, 0x11, 0x1F, 0x88, 0x89, 0x00, 0x0E,
+	0xDC, 0xCC, 0x6E, 0xE6, 0xDD, 0xDD, 0xD9, 0x99, 0xBB, 0xBB, 0x67, 0x63, 0x6E, 0x0E, 0xEC, 0xCC,
+	0xDD, 0xDC, 0x99, 0x9F, 0xBB, 0xB9, 0x33, 0x3E, 0x3C, 0x42, 0xB9, 0xA5, 0xB9, 0xA5, 0x42, 0x3C,
+	0x21, 0x04, 0x01, 0x11, 0xA8, 0x00, 0x1A, 0x13, 0xBE, 0x20, 0xFE, 0x23, 0x7D, 0xFE, 0x34, 0x20,
+	0xF5, 0x06, 0x19, 0x78, 0x86, 0x23, 0x05, 0x20, 0xFB, 0x86, 0x20, 0xFE, 0x3E, 0x01, 0xE0, 0x50,
+}
+
+// nintendoLogo returns the logo stored in the boot ROM,
+// which is compared against the one in the cartridge header.
+func nintendoLogo() []uint8 {
+	return dmgBoot[0xA8:0xD8]
+}
+
+// BootROM is the boot ROM, which is mapped at the beginning
+// of the address space until 0xFF50 is written.
+type BootROM struct {
+	data []uint8
+}
+
+// NewDMGBootROM returns the boot ROM of the DMG.
+func NewDMGBootROM() *BootROM {
+	return &BootROM{data: dmgBoot}
+}
+
+// NewBootROM returns a boot ROM of the model with the given content.
+func NewBootROM(m model.Model, data []uint8) (*BootROM, error) {
+	size := dmgBootSize
+	if m.IsCGB() {
+		size = cgbBootSize
+	}
+	if len(data) != size {
+		return nil, fmt.Errorf("%w: %s boot ROM must be %d bytes, got %d", ErrBootROMSize, m, size, len(data))
+	}
+	return &BootROM{data: data}, nil
+}
+
+// LoadBootROM reads the boot ROM of the model from a file.
+func LoadBootROM(m model.Model, path string) (*BootROM, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewBootROM(m, data)
+}
+
+// Contains returns true when the address is part of the address space.
+func (b *BootROM) Contains(addr uint16) bool {
+	if addr >= dmgBootSize && addr <= cgbHoleEnd {
+		return false
+	}
+	return int(addr) < len(b.data)
+}
+
+// Read returns the byte at the given address.
+func (b *BootROM) Read(addr uint16) uint8 {
+	return b.data[addr]
+}
+
+// Write is a no-op as this is a ROM.
+func (b *BootROM) Write(addr uint16, v uint8) {
+}
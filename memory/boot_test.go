@@ -0,0 +1,61 @@
+package memory_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/andreaperizzato/gameboy/memory"
+	"github.com/andreaperizzato/gameboy/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBootROM_Size(t *testing.T) {
+	tests := []struct {
+		name  string
+		model model.Model
+		size  int
+		valid bool
+	}{
+		{"DMG", model.DMG, 0x100, true},
+		{"DMG0", model.DMG0, 0x100, true},
+		{"MGB", model.MGB, 0x100, true},
+		{"SGB", model.SGB, 0x100, true},
+		{"CGB", model.CGB, 0x900, true},
+		{"AGB", model.AGB, 0x900, true},
+		{"DMG too big", model.DMG, 0x900, false},
+		{"CGB too small", model.CGB, 0x100, false},
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			_, err := memory.NewBootROM(tC.model, make([]uint8, tC.size))
+			if tC.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, memory.ErrBootROMSize))
+			}
+		})
+	}
+}
+
+func TestBootROM_CGBHole(t *testing.T) {
+	data := make([]uint8, 0x900)
+	data[0x00FF] = 0x11
+	data[0x0200] = 0x22
+	data[0x08FF] = 0x33
+	boot, err := memory.NewBootROM(model.CGB, data)
+	assert.NoError(t, err)
+
+	cart := memory.NewROM(make([]uint8, 0x8000), 0)
+	cart.Poke(0x0100, 0x44)
+	cart.Poke(0x0900, 0x55)
+	mmu := memory.NewMMU(boot, cart)
+	assert.Equal(t, uint8(0x11), mmu.Read(0x00FF))
+	assert.Equal(t, uint8(0x44), mmu.Read(0x0100), "cartridge header")
+	assert.Equal(t, uint8(0x22), mmu.Read(0x0200))
+	assert.Equal(t, uint8(0x33), mmu.Read(0x08FF))
+	assert.Equal(t, uint8(0x55), mmu.Read(0x0900), "after the boot ROM")
+
+	// Writing to the boot ROM has no effect.
+	mmu.Write(0x0200, 0x00)
+	assert.Equal(t, uint8(0x22), mmu.Read(0x0200))
+}
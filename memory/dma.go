@@ -0,0 +1,119 @@
+package memory
+
+// More info about OAM DMA can be found here:
+// https://gbdev.io/pandocs/#lcd-oam-dma-transfers
+
+const (
+	dmaAddr = uint16(0xFF46)
+	// dmaStartDelay is the number of M-cycles between
+	// writing the DMA register and the start of the transfer.
+	dmaStartDelay = 1
+)
+
+// DMA copies 160 bytes from XX00-XX9F to OAM when XX is written to 0xFF46.
+// A byte is copied every M-cycle and, while the transfer is running,
+// the CPU can only access HRAM and restart it. For this reason, the DMA sits
+// between the CPU and the memory: reading other addresses returns the byte
+// being transferred, or 0xFF for OAM, and writing them has no effect.
+type DMA struct {
+	mem AddressSpace
+	// ticks counts the T-cycles of the current M-cycle.
+	ticks uint8
+	// delay is the number of M-cycles before the requested transfer starts.
+	delay uint8
+	// next is the source address of the requested transfer.
+	next uint16
+	// source is the source address of the running transfer.
+	source uint16
+	// index is the number of bytes transferred so far.
+	index uint16
+	// active is true while a transfer is running.
+	active bool
+	// last is the last byte transferred.
+	last uint8
+}
+
+// NewDMA creates a new DMA copying on the given memory,
+// which is started by writing the DMA register in io.
+func NewDMA(mem AddressSpace, io *IO) *DMA {
+	d := &DMA{mem: mem}
+	io.Subscribe(dmaAddr, d.request)
+	return d
+}
+
+func (d *DMA) request(v uint8) {
+	d.next = uint16(v) << 8
+	// Sources above 0xDFFF read from WRAM, like the echo RAM.
+	if d.next >= echoStart {
+		d.next -= echoStart - wramStart
+	}
+	d.delay = dmaStartDelay
+}
+
+// Active returns true while a transfer is running.
+func (d *DMA) Active() bool {
+	return d.active
+}
+
+// Tick advances the DMA by one T-cycle.
+func (d *DMA) Tick() {
+	d.ticks++
+	if d.ticks < 4 {
+		return
+	}
+	d.ticks = 0
+	if d.active {
+		d.last = d.mem.Read(d.source + d.index)
+		d.mem.Write(oamStart+d.index, d.last)
+		d.index++
+		d.active = d.index < oamSize
+	}
+	if d.delay > 0 {
+		d.delay--
+		if d.delay == 0 {
+			d.active = true
+			d.source = d.next
+			d.index = 0
+		}
+	}
+}
+
+// Peek returns the byte stored at the given address,
+// regardless of any running transfer.
+func (d *DMA) Peek(addr uint16) uint8 {
+	return Peek(d.mem, addr)
+}
+
+// Poke changes the byte stored at the given address,
+// regardless of any running transfer.
+func (d *DMA) Poke(addr uint16, v uint8) {
+	Poke(d.mem, addr, v)
+}
+
+func isHRAM(addr uint16) bool {
+	return addr >= hramStart && addr < hramStart+hramSize
+}
+
+// Contains returns true when the address is part of the address space.
+func (d *DMA) Contains(addr uint16) bool {
+	return d.mem.Contains(addr)
+}
+
+// Read returns the byte at the given address.
+func (d *DMA) Read(addr uint16) uint8 {
+	if !d.active || isHRAM(addr) {
+		return d.mem.Read(addr)
+	}
+	if addr >= oamStart && addr < oamStart+oamSize {
+		return 0xFF
+	}
+	return d.last
+}
+
+// Write writes a value at the given address.
+// Writing the DMA register during a transfer restarts it.
+func (d *DMA) Write(addr uint16, v uint8) {
+	if !d.active || isHRAM(addr) || addr == dmaAddr {
+		d.mem.Write(addr, v)
+	}
+}
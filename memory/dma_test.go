@@ -0,0 +1,91 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/andreaperizzato/gameboy/memory"
+	"github.com/andreaperizzato/gameboy/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func newDMA() (*memory.DMA, *memory.MMU) {
+	m := memory.NewDMGMap(model.DMG, memory.NewROM(nil, 0))
+	mmu := memory.NewMMU(memory.NewROM(nil, 0), m.Spaces()...)
+	return memory.NewDMA(mmu, m.IO), mmu
+}
+
+func tickDMA(d *memory.DMA, mcycles int) {
+	for i := 0; i < mcycles*4; i++ {
+		d.Tick()
+	}
+}
+
+func TestDMA_Transfer(t *testing.T) {
+	d, mmu := newDMA()
+	for i := uint16(0); i < 0xA0; i++ {
+		mmu.Write(0xC100+i, uint8(i+1))
+	}
+	mmu.Write(0xC000, 0x42)
+	mmu.Write(0xFF80, 0x24)
+
+	d.Write(0xFF46, 0xC1)
+	assert.Equal(t, uint8(0xC1), d.Read(0xFF46), "DMA register")
+	assert.False(t, d.Active(), "start delay")
+	tickDMA(d, 1)
+	assert.True(t, d.Active(), "started")
+
+	// One byte is copied every M-cycle.
+	tickDMA(d, 1)
+	assert.Equal(t, uint8(0x01), mmu.Read(0xFE00))
+	assert.Equal(t, uint8(0x00), mmu.Read(0xFE01))
+
+	// The CPU can only access HRAM.
+	assert.Equal(t, uint8(0x24), d.Read(0xFF80), "HRAM")
+	assert.Equal(t, uint8(0x01), d.Read(0xC000), "bus conflict")
+	assert.Equal(t, uint8(0xFF), d.Read(0xFE00), "OAM")
+	d.Write(0xC000, 0x00)
+	assert.Equal(t, uint8(0x42), mmu.Read(0xC000), "write ignored")
+	d.Write(0xFF81, 0x11)
+	assert.Equal(t, uint8(0x11), mmu.Read(0xFF81), "HRAM write")
+
+	tickDMA(d, 159)
+	assert.False(t, d.Active(), "done after 160 M-cycles")
+	for i := uint16(0); i < 0xA0; i++ {
+		assert.Equal(t, uint8(i+1), mmu.Read(0xFE00+i), "OAM at 0x%04X", 0xFE00+i)
+	}
+	assert.Equal(t, uint8(0x42), d.Read(0xC000), "bus released")
+}
+
+func TestDMA_EchoSource(t *testing.T) {
+	d, mmu := newDMA()
+	mmu.Write(0xDE00, 0x42)
+
+	d.Write(0xFF46, 0xFE)
+	tickDMA(d, 2)
+	assert.Equal(t, uint8(0x42), mmu.Read(0xFE00))
+}
+
+func TestDMA_Restart(t *testing.T) {
+	d, mmu := newDMA()
+	for i := uint16(0); i < 0xA0; i++ {
+		mmu.Write(0xC100+i, uint8(i+1))
+		mmu.Write(0xC200+i, uint8(0x80+i))
+	}
+
+	d.Write(0xFF46, 0xC1)
+	tickDMA(d, 1+10)
+	d.Write(0xFF46, 0xC2)
+
+	// The transfer goes on during the start delay of the new one.
+	tickDMA(d, 1)
+	assert.Equal(t, uint8(11), mmu.Read(0xFE0A), "old transfer")
+	assert.True(t, d.Active(), "restarted")
+
+	tickDMA(d, 159)
+	assert.True(t, d.Active(), "160 M-cycles from the restart")
+	tickDMA(d, 1)
+	assert.False(t, d.Active(), "done")
+	for i := uint16(0); i < 0xA0; i++ {
+		assert.Equal(t, uint8(0x80+i), mmu.Read(0xFE00+i), "OAM at 0x%04X", 0xFE00+i)
+	}
+}
@@ -0,0 +1,236 @@
+package memory
+
+import "github.com/andreaperizzato/gameboy/model"
+
+// More info about the IO registers can be found here:
+// https://gbdev.io/pandocs/#io-ranges
+
+// IORegister describes how the CPU sees an IO register.
+type IORegister struct {
+	// Readable are the bits the CPU can read.
+	Readable uint8
+	// Writable are the bits the CPU can write.
+	Writable uint8
+	// Unused is the value of the bits which are not readable.
+	Unused uint8
+}
+
+// Common kinds of registers.
+var (
+	readWrite = IORegister{Readable: 0xFF, Writable: 0xFF}
+	readOnly  = IORegister{Readable: 0xFF}
+	writeOnly = IORegister{Writable: 0xFF, Unused: 0xFF}
+)
+
+// dmgRegisters are the registers defined by the DMG.
+// The timer and the interrupt flags are handled by their own address spaces.
+var dmgRegisters = map[uint16]IORegister{
+	0xFF00: {Readable: 0x3F, Writable: 0x30, Unused: 0xC0}, // P1
+	0xFF01: readWrite,                                      // SB
+	0xFF02: {Readable: 0x81, Writable: 0x81, Unused: 0x7E}, // SC
+	0xFF10: {Readable: 0x7F, Writable: 0x7F, Unused: 0x80}, // NR10
+	0xFF11: {Readable: 0xC0, Writable: 0xFF, Unused: 0x3F}, // NR11
+	0xFF12: readWrite,                                      // NR12
+	0xFF13: writeOnly,                                      // NR13
+	0xFF14: {Readable: 0x40, Writable: 0xC7, Unused: 0xBF}, // NR14
+	0xFF16: {Readable: 0xC0, Writable: 0xFF, Unused: 0x3F}, // NR21
+	0xFF17: readWrite,                                      // NR22
+	0xFF18: writeOnly,                                      // NR23
+	0xFF19: {Readable: 0x40, Writable: 0xC7, Unused: 0xBF}, // NR24
+	0xFF1A: {Readable: 0x80, Writable: 0x80, Unused: 0x7F}, // NR30
+	0xFF1B: writeOnly,                                      // NR31
+	0xFF1C: {Readable: 0x60, Writable: 0x60, Unused: 0x9F}, // NR32
+	0xFF1D: writeOnly,                                      // NR33
+	0xFF1E: {Readable: 0x40, Writable: 0xC7, Unused: 0xBF}, // NR34
+	0xFF20: {Writable: 0x3F, Unused: 0xFF},                 // NR41
+	0xFF21: readWrite,                                      // NR42
+	0xFF22: readWrite,                                      // NR43
+	0xFF23: {Readable: 0x40, Writable: 0xC0, Unused: 0xBF}, // NR44
+	0xFF24: readWrite,                                      // NR50
+	0xFF25: readWrite,                                      // NR51
+	0xFF26: {Readable: 0x8F, Writable: 0x80, Unused: 0x70}, // NR52
+	0xFF40: readWrite,                                      // LCDC
+	0xFF41: {Readable: 0x7F, Writable: 0x78, Unused: 0x80}, // STAT
+	0xFF42: readWrite,                                      // SCY
+	0xFF43: readWrite,                                      // SCX
+	0xFF44: readOnly,                                       // LY
+	0xFF45: readWrite,                                      // LYC
+	0xFF46: readWrite,                                      // DMA
+	0xFF47: readWrite,                                      // BGP
+	0xFF48: readWrite,                                      // OBP0
+	0xFF49: readWrite,                                      // OBP1
+	0xFF4A: readWrite,                                      // WY
+	0xFF4B: readWrite,                                      // WX
+}
+
+// cgbRegisters are the registers added by the CGB.
+var cgbRegisters = map[uint16]IORegister{
+	0xFF4D: {Readable: 0x81, Writable: 0x01, Unused: 0x7E}, // KEY1
+}
+
+// postBootRegisters are the values of the registers left by the boot ROM.
+// https://gbdev.io/pandocs/#power-up-sequence
+var postBootRegisters = map[uint16]uint8{
+	0xFF00: 0xCF, // P1
+	0xFF01: 0x00, // SB
+	0xFF02: 0x7E, // SC
+	0xFF10: 0x80, // NR10
+	0xFF11: 0xBF, // NR11
+	0xFF12: 0xF3, // NR12
+	0xFF13: 0xFF, // NR13
+	0xFF14: 0xBF, // NR14
+	0xFF16: 0x3F, // NR21
+	0xFF17: 0x00, // NR22
+	0xFF18: 0xFF, // NR23
+	0xFF19: 0xBF, // NR24
+	0xFF1A: 0x7F, // NR30
+	0xFF1B: 0xFF, // NR31
+	0xFF1C: 0x9F, // NR32
+	0xFF1D: 0xFF, // NR33
+	0xFF1E: 0xBF, // NR34
+	0xFF20: 0xFF, // NR41
+	0xFF21: 0x00, // NR42
+	0xFF22: 0x00, // NR43
+	0xFF23: 0xBF, // NR44
+	0xFF24: 0x77, // NR50
+	0xFF25: 0xF3, // NR51
+	0xFF26: 0xF1, // NR52
+	0xFF40: 0x91, // LCDC
+	0xFF41: 0x85, // STAT
+	0xFF42: 0x00, // SCY
+	0xFF43: 0x00, // SCX
+	0xFF44: 0x00, // LY
+	0xFF45: 0x00, // LYC
+	0xFF46: 0xFF, // DMA
+	0xFF47: 0xFC, // BGP
+	0xFF48: 0xFF, // OBP0
+	0xFF49: 0xFF, // OBP1
+	0xFF4A: 0x00, // WY
+	0xFF4B: 0x00, // WX
+}
+
+// ioRegister is the state of a register in the IO region.
+type ioRegister struct {
+	IORegister
+	defined bool
+	value   uint8
+	hooks   []func(v uint8)
+}
+
+// IO is the IO region at 0xFF00-0xFF7F.
+// The CPU can only access the bits declared for each register,
+// reading undefined registers returns 0xFF and writing them has no effect.
+// Components owning the registers access them through Raw.
+type IO struct {
+	regs [ioSize]ioRegister
+}
+
+// NewIO creates the IO region with the registers of the model.
+func NewIO(m model.Model) *IO {
+	io := &IO{}
+	for addr, r := range dmgRegisters {
+		io.Define(addr, r)
+	}
+	if m.IsCGB() {
+		for addr, r := range cgbRegisters {
+			io.Define(addr, r)
+		}
+	}
+	// The joypad inputs are high when no button is pressed.
+	io.Raw().Write(0xFF00, 0x0F)
+	// Wave RAM.
+	for addr := uint16(0xFF30); addr <= 0xFF3F; addr++ {
+		io.Define(addr, readWrite)
+	}
+	return io
+}
+
+// SkipBoot sets the registers as the boot ROM of the model leaves them.
+func (io *IO) SkipBoot(m model.Model) {
+	for addr, v := range postBootRegisters {
+		io.Poke(addr, v)
+	}
+	if m == model.SGB {
+		// The SGB boot ROM doesn't play the sound,
+		// so channel 1 isn't left on.
+		io.Poke(0xFF26, 0xF0)
+	}
+}
+
+// Define declares the register at the given address.
+func (io *IO) Define(addr uint16, r IORegister) {
+	reg := &io.regs[addr-ioStart]
+	reg.IORegister = r
+	reg.defined = true
+}
+
+// Subscribe registers a function which is called
+// with the value written by the CPU to the register at the given address.
+func (io *IO) Subscribe(addr uint16, f func(v uint8)) {
+	reg := &io.regs[addr-ioStart]
+	reg.hooks = append(reg.hooks, f)
+}
+
+// Contains returns true when the address is part of the address space.
+func (io *IO) Contains(addr uint16) bool {
+	return addr >= ioStart && addr < ioStart+ioSize
+}
+
+// Read returns the byte at the given address.
+func (io *IO) Read(addr uint16) uint8 {
+	reg := &io.regs[addr-ioStart]
+	if !reg.defined {
+		return 0xFF
+	}
+	return reg.value&reg.Readable | reg.Unused&^reg.Readable
+}
+
+// Write writes a value at the given address.
+func (io *IO) Write(addr uint16, v uint8) {
+	reg := &io.regs[addr-ioStart]
+	if !reg.defined {
+		return
+	}
+	reg.value = reg.value&^reg.Writable | v&reg.Writable
+	for _, f := range reg.hooks {
+		f(v)
+	}
+}
+
+// Peek returns the value of the register at the given address,
+// including the bits the CPU can't read.
+func (io *IO) Peek(addr uint16) uint8 {
+	return io.regs[addr-ioStart].value
+}
+
+// Poke sets the value of the register at the given address
+// without notifying subscribers.
+func (io *IO) Poke(addr uint16, v uint8) {
+	io.regs[addr-ioStart].value = v
+}
+
+// Raw returns a view of the registers where all the bits
+// can be read and written and subscribers are not notified.
+func (io *IO) Raw() AddressSpace {
+	return rawIO{io}
+}
+
+// rawIO accesses the values of the IO registers.
+type rawIO struct {
+	io *IO
+}
+
+// Contains returns true when the address is part of the address space.
+func (r rawIO) Contains(addr uint16) bool {
+	return r.io.Contains(addr)
+}
+
+// Read returns the byte at the given address.
+func (r rawIO) Read(addr uint16) uint8 {
+	return r.io.Peek(addr)
+}
+
+// Write writes a value at the given address.
+func (r rawIO) Write(addr uint16, v uint8) {
+	r.io.Poke(addr, v)
+}
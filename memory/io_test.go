@@ -0,0 +1,71 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/andreaperizzato/gameboy/memory"
+	"github.com/andreaperizzato/gameboy/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIO_ReadWrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		addr    uint16
+		write   uint8
+		expRead uint8
+		expRaw  uint8
+	}{
+		{"read-write", 0xFF40, 0x5A, 0x5A, 0x5A},
+		{"read-only", 0xFF44, 0x5A, 0x00, 0x00},
+		{"write-only", 0xFF13, 0x5A, 0xFF, 0x5A},
+		{"unused bits", 0xFF41, 0x00, 0x80, 0x00},
+		{"read-only bits", 0xFF41, 0xFF, 0xF8, 0x78},
+		{"partially readable", 0xFF14, 0xFF, 0xFF, 0xC7},
+		{"undefined", 0xFF03, 0x5A, 0xFF, 0x00},
+		{"wave RAM", 0xFF30, 0x5A, 0x5A, 0x5A},
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			io := memory.NewIO(model.DMG)
+			io.Write(tC.addr, tC.write)
+			assert.Equal(t, tC.expRead, io.Read(tC.addr), "read")
+			assert.Equal(t, tC.expRaw, io.Raw().Read(tC.addr), "raw")
+		})
+	}
+}
+
+func TestIO_Raw(t *testing.T) {
+	io := memory.NewIO(model.DMG)
+	assert.Equal(t, uint8(0xCF), io.Read(0xFF00), "no button pressed")
+
+	// The owner of a register can set the bits the CPU can't.
+	io.Raw().Write(0xFF44, 0x90)
+	assert.Equal(t, uint8(0x90), io.Read(0xFF44))
+	io.Raw().Write(0xFF41, 0x03)
+	io.Write(0xFF41, 0x40)
+	assert.Equal(t, uint8(0xC3), io.Read(0xFF41), "mode bits are kept")
+}
+
+func TestIO_Subscribe(t *testing.T) {
+	io := memory.NewIO(model.DMG)
+	var written []uint8
+	io.Subscribe(0xFF14, func(v uint8) { written = append(written, v) })
+
+	io.Write(0xFF14, 0x87)
+	io.Raw().Write(0xFF14, 0x01)
+	io.Write(0xFF13, 0x12)
+	assert.Equal(t, []uint8{0x87}, written, "only CPU writes to the register are notified")
+}
+
+func TestIO_SkipBoot(t *testing.T) {
+	io := memory.NewIO(model.DMG)
+	io.SkipBoot(model.DMG)
+	assert.Equal(t, uint8(0x91), io.Read(0xFF40), "LCDC")
+	assert.Equal(t, uint8(0x85), io.Read(0xFF41), "STAT")
+	assert.Equal(t, uint8(0xFC), io.Read(0xFF47), "BGP")
+	assert.Equal(t, uint8(0xF1), io.Read(0xFF26), "NR52")
+
+	io.SkipBoot(model.SGB)
+	assert.Equal(t, uint8(0xF0), io.Read(0xFF26), "NR52")
+}
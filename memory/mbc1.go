@@ -0,0 +1,184 @@
+package memory
+
+import (
+	"bytes"
+	"io"
+)
+
+// More info about MBC1 can be found here:
+// https://gbdev.io/pandocs/#mbc1
+// https://github.com/Gekkio/gb-ctr (Game Boy: Complete Technical Reference)
+
+const (
+	romBankSize = 0x4000
+	ramBankSize = 0x2000
+
+	// Cartridge RAM is mapped at 0xA000-0xBFFF.
+	cartRAMStart = uint16(0xA000)
+	cartRAMEnd   = uint16(0xBFFF)
+	// Cartridge ROM is mapped at 0x0000-0x7FFF.
+	cartROMEnd = uint16(0x7FFF)
+
+	// Writing this value in the low nibble of the RAM
+	// enable register enables cartridge RAM.
+	ramEnableValue = 0x0A
+)
+
+// MBC1 is the Memory Bank Controller 1. It's an address space
+// mapping the cartridge ROM and RAM, with their bank registers.
+type MBC1 struct {
+	rom []uint8
+	ram []uint8
+
+	// multicart is true for MBC1M cartridges where the
+	// bank1 register is wired to only 4 bits of the ROM bank.
+	multicart bool
+
+	ramEnabled bool
+	// bank1 is the 5-bit register with the low bits of the ROM bank.
+	bank1 uint8
+	// bank2 is the 2-bit register with the high bits of the ROM bank
+	// or the RAM bank, depending on the mode.
+	bank2 uint8
+	// mode selects the banking mode: when true, bank2 also affects
+	// the 0x0000-0x3FFF region and the RAM bank.
+	mode bool
+}
+
+// NewMBC1 creates a new MBC1 with the given ROM and the given size of RAM.
+// MBC1M multicarts are detected from the content of the ROM.
+func NewMBC1(rom []uint8, ramSize int) *MBC1 {
+	return &MBC1{
+		rom:       rom,
+		ram:       make([]uint8, ramSize),
+		multicart: isMBC1M(rom),
+		bank1:     1,
+	}
+}
+
+// isMBC1M returns true when the ROM is an MBC1M multicart.
+// These are 1MiB cartridges with a game every 16 banks and, as there is no
+// flag in the header, they're detected looking for the Nintendo logo in
+// the header of the second game.
+func isMBC1M(rom []uint8) bool {
+	const (
+		multicartSize = 64 * romBankSize
+		logoAddr      = 0x0104
+		secondGame    = 0x10 * romBankSize
+	)
+	if len(rom) != multicartSize {
+		return false
+	}
+	logo := nintendoLogo()
+	return bytes.Equal(rom[secondGame+logoAddr:secondGame+logoAddr+len(logo)], logo)
+}
+
+// Contains returns true when the address is part of the address space.
+func (m *MBC1) Contains(addr uint16) bool {
+	return addr <= cartROMEnd || (addr >= cartRAMStart && addr <= cartRAMEnd)
+}
+
+// Read returns the byte at the given address.
+func (m *MBC1) Read(addr uint16) uint8 {
+	if addr >= cartRAMStart && !m.ramEnabled {
+		return 0xFF
+	}
+	return m.Peek(addr)
+}
+
+// Peek returns the byte mapped at the given address,
+// even when RAM is disabled.
+func (m *MBC1) Peek(addr uint16) uint8 {
+	if addr <= cartROMEnd {
+		return m.rom[m.romAddr(addr)]
+	}
+	if len(m.ram) == 0 {
+		return 0xFF
+	}
+	return m.ram[m.ramAddr(addr)]
+}
+
+// Poke changes the byte mapped at the given address,
+// without changing the bank registers.
+func (m *MBC1) Poke(addr uint16, v uint8) {
+	if addr <= cartROMEnd {
+		m.rom[m.romAddr(addr)] = v
+		return
+	}
+	if len(m.ram) > 0 {
+		m.ram[m.ramAddr(addr)] = v
+	}
+}
+
+// Write writes a value at the given address.
+// Writes to the ROM area set the bank registers.
+func (m *MBC1) Write(addr uint16, v uint8) {
+	switch {
+	case addr < 0x2000:
+		m.ramEnabled = v&0x0F == ramEnableValue
+	case addr < 0x4000:
+		// Writing 0 selects bank 1, which also happens
+		// when the 5 bits are 0 but the value isn't (e.g. 0x20).
+		m.bank1 = v & 0x1F
+		if m.bank1 == 0 {
+			m.bank1 = 1
+		}
+	case addr < 0x6000:
+		m.bank2 = v & 0x03
+	case addr <= cartROMEnd:
+		m.mode = v&0x01 == 1
+	default:
+		if !m.ramEnabled || len(m.ram) == 0 {
+			return
+		}
+		m.ram[m.ramAddr(addr)] = v
+	}
+}
+
+// lowBank returns the bits of the ROM bank coming from bank1.
+func (m *MBC1) lowBank() int {
+	if m.multicart {
+		return int(m.bank1 & 0x0F)
+	}
+	return int(m.bank1)
+}
+
+// highBank returns the bits of the ROM bank coming from bank2.
+func (m *MBC1) highBank() int {
+	if m.multicart {
+		return int(m.bank2) << 4
+	}
+	return int(m.bank2) << 5
+}
+
+func (m *MBC1) romAddr(addr uint16) int {
+	bank := 0
+	switch {
+	case addr >= romBankSize:
+		bank = m.highBank() | m.lowBank()
+		addr -= romBankSize
+	case m.mode:
+		bank = m.highBank()
+	}
+	// Banks that don't exist wrap around as the
+	// unused high bits of the bank number are not connected.
+	return (bank*romBankSize + int(addr)) % len(m.rom)
+}
+
+func (m *MBC1) ramAddr(addr uint16) int {
+	bank := 0
+	if m.mode {
+		bank = int(m.bank2)
+	}
+	return (bank*ramBankSize + int(addr-cartRAMStart)) % len(m.ram)
+}
+
+// Save writes the content of the RAM.
+func (m *MBC1) Save(w io.Writer) error {
+	return saveRAM(w, m.ram)
+}
+
+// Load reads the content of the RAM as written by Save.
+func (m *MBC1) Load(r io.Reader) error {
+	return loadRAM(r, m.ram)
+}
@@ -0,0 +1,101 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/andreaperizzato/gameboy/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMBC1_ROMBanks(t *testing.T) {
+	m := memory.NewMBC1(newBankedROM(128), 0)
+
+	assert.Equal(t, uint8(0x00), m.Read(0x0000), "bank 0")
+	assert.Equal(t, uint8(0x01), m.Read(0x4000), "bank 1 by default")
+
+	m.Write(0x2000, 0x05)
+	assert.Equal(t, uint8(0x05), m.Read(0x7FFF), "bank 5")
+
+	// Writing 0 selects bank 1.
+	m.Write(0x2000, 0x00)
+	assert.Equal(t, uint8(0x01), m.Read(0x4000), "bank 0 -> 1")
+	// Also when only the low 5 bits are 0.
+	m.Write(0x2000, 0x20)
+	assert.Equal(t, uint8(0x01), m.Read(0x4000), "bank 0x20 -> 1")
+
+	// The secondary register sets bits 5-6.
+	m.Write(0x4000, 0x02)
+	m.Write(0x2000, 0x03)
+	assert.Equal(t, uint8(0x43), m.Read(0x4000), "bank 0x43")
+	assert.Equal(t, uint8(0x00), m.Read(0x0000), "bank 0 in mode 0")
+
+	// In mode 1, it also applies to the first region.
+	m.Write(0x6000, 0x01)
+	assert.Equal(t, uint8(0x40), m.Read(0x0000), "bank 0x40 in mode 1")
+}
+
+func TestMBC1_ROMBankWrapAround(t *testing.T) {
+	m := memory.NewMBC1(newBankedROM(4), 0)
+	m.Write(0x2000, 0x06)
+	assert.Equal(t, uint8(0x02), m.Read(0x4000), "bank 6 of 4")
+}
+
+func TestMBC1_RAM(t *testing.T) {
+	m := memory.NewMBC1(newBankedROM(4), 32*1024)
+	assert.True(t, m.Contains(0xA000))
+	assert.True(t, m.Contains(0xBFFF))
+	assert.False(t, m.Contains(0xC000))
+
+	// RAM is disabled by default.
+	m.Write(0xA000, 0x11)
+	assert.Equal(t, uint8(0xFF), m.Read(0xA000), "disabled")
+
+	m.Write(0x0000, 0x0A)
+	m.Write(0xA000, 0x11)
+	assert.Equal(t, uint8(0x11), m.Read(0xA000), "enabled")
+
+	// The RAM bank is only selected in mode 1.
+	m.Write(0x4000, 0x02)
+	assert.Equal(t, uint8(0x11), m.Read(0xA000), "bank 0 in mode 0")
+	m.Write(0x6000, 0x01)
+	assert.Equal(t, uint8(0x00), m.Read(0xA000), "bank 2 in mode 1")
+	m.Write(0xA000, 0x22)
+	m.Write(0x6000, 0x00)
+	assert.Equal(t, uint8(0x11), m.Read(0xA000), "back to bank 0")
+
+	m.Write(0x0000, 0x00)
+	assert.Equal(t, uint8(0xFF), m.Read(0xA000), "disabled again")
+}
+
+func TestMBC1_NoRAM(t *testing.T) {
+	m := memory.NewMBC1(newBankedROM(4), 0)
+	m.Write(0x0000, 0x0A)
+	m.Write(0xA000, 0x11)
+	assert.Equal(t, uint8(0xFF), m.Read(0xA000))
+}
+
+func TestMBC1_Multicart(t *testing.T) {
+	rom := newBankedROM(64)
+	logo := memory.NewDMGBootROM()
+	for i := uint16(0); i < 48; i++ {
+		rom[0x10*0x4000+0x0104+int(i)] = logo.Read(0xA8 + i)
+	}
+	m := memory.NewMBC1(rom, 0)
+
+	// Only 4 bits of bank1 are used and bank2 provides bits 4-5.
+	m.Write(0x2000, 0x13)
+	assert.Equal(t, uint8(0x03), m.Read(0x4000), "bank 3")
+	m.Write(0x4000, 0x01)
+	assert.Equal(t, uint8(0x13), m.Read(0x4000), "bank 0x13")
+	m.Write(0x6000, 0x01)
+	assert.Equal(t, uint8(0x10), m.Read(0x0000), "bank 0x10 in mode 1")
+}
+
+// newBankedROM returns a ROM where every byte of a bank is the bank number.
+func newBankedROM(banks int) []uint8 {
+	rom := make([]uint8, banks*0x4000)
+	for i := range rom {
+		rom[i] = uint8(i / 0x4000)
+	}
+	return rom
+}
@@ -0,0 +1,114 @@
+package memory
+
+import "io"
+
+// More info about MBC2 can be found here:
+// https://gbdev.io/pandocs/#mbc2
+
+const (
+	// MBC2 has 512 half-bytes of RAM built in.
+	mbc2RAMSize = 512
+	// Bit 8 of the address selects which register is written
+	// when writing to 0x0000-0x3FFF.
+	mbc2RegisterSelect = uint16(1 << 8)
+)
+
+// MBC2 is the Memory Bank Controller 2. It's an address space
+// mapping the cartridge ROM and its built-in 512x4-bit RAM.
+type MBC2 struct {
+	rom []uint8
+	// ram holds one nibble per byte.
+	ram []uint8
+
+	ramEnabled bool
+	// romBank is the 4-bit ROM bank mapped at 0x4000-0x7FFF.
+	romBank uint8
+}
+
+// NewMBC2 creates a new MBC2 with the given ROM.
+func NewMBC2(rom []uint8) *MBC2 {
+	return &MBC2{
+		rom:     rom,
+		ram:     make([]uint8, mbc2RAMSize),
+		romBank: 1,
+	}
+}
+
+// Contains returns true when the address is part of the address space.
+func (m *MBC2) Contains(addr uint16) bool {
+	return addr <= cartROMEnd || (addr >= cartRAMStart && addr <= cartRAMEnd)
+}
+
+// Read returns the byte at the given address.
+func (m *MBC2) Read(addr uint16) uint8 {
+	if addr >= cartRAMStart && !m.ramEnabled {
+		return 0xFF
+	}
+	return m.Peek(addr)
+}
+
+// Peek returns the byte mapped at the given address,
+// even when RAM is disabled.
+func (m *MBC2) Peek(addr uint16) uint8 {
+	if addr <= cartROMEnd {
+		return m.rom[m.romAddr(addr)]
+	}
+	// Only the lower nibble is stored, the upper one reads 1.
+	return m.ram[m.ramAddr(addr)] | 0xF0
+}
+
+// Poke changes the byte mapped at the given address,
+// without changing the registers.
+func (m *MBC2) Poke(addr uint16, v uint8) {
+	if addr <= cartROMEnd {
+		m.rom[m.romAddr(addr)] = v
+		return
+	}
+	m.ram[m.ramAddr(addr)] = v & 0x0F
+}
+
+// Write writes a value at the given address.
+// Writes to 0x0000-0x3FFF set the registers.
+func (m *MBC2) Write(addr uint16, v uint8) {
+	switch {
+	case addr < romBankSize:
+		if addr&mbc2RegisterSelect == 0 {
+			m.ramEnabled = v&0x0F == ramEnableValue
+			return
+		}
+		m.romBank = v & 0x0F
+		if m.romBank == 0 {
+			m.romBank = 1
+		}
+	case addr <= cartROMEnd:
+		// Nothing is mapped here.
+	default:
+		if !m.ramEnabled {
+			return
+		}
+		m.ram[m.ramAddr(addr)] = v & 0x0F
+	}
+}
+
+func (m *MBC2) romAddr(addr uint16) int {
+	if addr < romBankSize {
+		return int(addr)
+	}
+	return (int(m.romBank)*romBankSize + int(addr-romBankSize)) % len(m.rom)
+}
+
+// ramAddr returns the index in RAM for the address.
+// The 512 bytes are repeated through the whole 0xA000-0xBFFF region.
+func (m *MBC2) ramAddr(addr uint16) int {
+	return int(addr-cartRAMStart) % mbc2RAMSize
+}
+
+// Save writes the content of the built-in RAM, one nibble per byte.
+func (m *MBC2) Save(w io.Writer) error {
+	return saveRAM(w, m.ram)
+}
+
+// Load reads the content of the RAM as written by Save.
+func (m *MBC2) Load(r io.Reader) error {
+	return loadRAM(r, m.ram)
+}
@@ -0,0 +1,38 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/andreaperizzato/gameboy/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMBC2_Registers(t *testing.T) {
+	m := memory.NewMBC2(newBankedROM(16))
+
+	// With bit 8 of the address set, it selects the ROM bank.
+	m.Write(0x2100, 0x05)
+	assert.Equal(t, uint8(0x05), m.Read(0x4000), "bank 5")
+	m.Write(0x0100, 0x00)
+	assert.Equal(t, uint8(0x01), m.Read(0x4000), "bank 0 -> 1")
+
+	// Otherwise it enables the RAM.
+	m.Write(0x0000, 0x0A)
+	m.Write(0xA000, 0x01)
+	assert.Equal(t, uint8(0xF1), m.Read(0xA000), "RAM enabled")
+	m.Write(0x2000, 0x00)
+	assert.Equal(t, uint8(0xFF), m.Read(0xA000), "RAM disabled")
+	assert.Equal(t, uint8(0x01), m.Read(0x4000), "bank unchanged")
+}
+
+func TestMBC2_RAM(t *testing.T) {
+	m := memory.NewMBC2(newBankedROM(4))
+	m.Write(0x0000, 0x0A)
+
+	// Only the lower nibble is stored.
+	m.Write(0xA010, 0xAB)
+	assert.Equal(t, uint8(0xFB), m.Read(0xA010))
+	// The 512 bytes are repeated through the whole region.
+	assert.Equal(t, uint8(0xFB), m.Read(0xA210))
+	assert.Equal(t, uint8(0xFB), m.Read(0xBE10))
+}
@@ -0,0 +1,163 @@
+package memory
+
+import "io"
+
+// More info about MBC3 can be found here:
+// https://gbdev.io/pandocs/#mbc3
+
+// MBC3 is the Memory Bank Controller 3. It's an address space
+// mapping the cartridge ROM, RAM and, optionally, a real-time clock.
+type MBC3 struct {
+	rom []uint8
+	ram []uint8
+	rtc *RTC
+
+	// ramEnabled enables both RAM and RTC.
+	ramEnabled bool
+	// romBank is the 7-bit ROM bank mapped at 0x4000-0x7FFF.
+	romBank uint8
+	// ramBank selects a RAM bank (0x00-0x03) or an RTC register (0x08-0x0C).
+	ramBank uint8
+	// latchArmed is true after writing 0 to the latch register,
+	// so that writing 1 next latches the clock.
+	latchArmed bool
+}
+
+// NewMBC3 creates a new MBC3 with the given ROM and the given size of RAM.
+// Set rtc to nil for cartridges without a real-time clock.
+func NewMBC3(rom []uint8, ramSize int, rtc *RTC) *MBC3 {
+	return &MBC3{
+		rom:     rom,
+		ram:     make([]uint8, ramSize),
+		rtc:     rtc,
+		romBank: 1,
+	}
+}
+
+// Tick advances the real-time clock by one cycle.
+func (m *MBC3) Tick() {
+	if m.rtc != nil {
+		m.rtc.Tick()
+	}
+}
+
+// Contains returns true when the address is part of the address space.
+func (m *MBC3) Contains(addr uint16) bool {
+	return addr <= cartROMEnd || (addr >= cartRAMStart && addr <= cartRAMEnd)
+}
+
+// Read returns the byte at the given address.
+func (m *MBC3) Read(addr uint16) uint8 {
+	if addr >= cartRAMStart && !m.ramEnabled {
+		return 0xFF
+	}
+	return m.Peek(addr)
+}
+
+// Peek returns the byte mapped at the given address,
+// even when RAM is disabled.
+func (m *MBC3) Peek(addr uint16) uint8 {
+	switch {
+	case addr <= cartROMEnd:
+		return m.rom[m.romAddr(addr)]
+	case m.ramBank >= 0x08:
+		if m.rtc == nil || m.ramBank > 0x0C {
+			return 0xFF
+		}
+		return m.rtc.Read(m.ramBank)
+	case len(m.ram) == 0:
+		return 0xFF
+	default:
+		return m.ram[m.ramAddr(addr)]
+	}
+}
+
+// Poke changes the byte mapped at the given address,
+// without changing the bank registers.
+func (m *MBC3) Poke(addr uint16, v uint8) {
+	switch {
+	case addr <= cartROMEnd:
+		m.rom[m.romAddr(addr)] = v
+	case m.ramBank >= 0x08:
+		if m.rtc != nil && m.ramBank <= 0x0C {
+			m.rtc.Write(m.ramBank, v)
+		}
+	case len(m.ram) > 0:
+		m.ram[m.ramAddr(addr)] = v
+	}
+}
+
+// Write writes a value at the given address.
+// Writes to the ROM area set the bank registers.
+func (m *MBC3) Write(addr uint16, v uint8) {
+	switch {
+	case addr < 0x2000:
+		m.ramEnabled = v&0x0F == ramEnableValue
+	case addr < 0x4000:
+		m.romBank = v & 0x7F
+		if m.romBank == 0 {
+			m.romBank = 1
+		}
+	case addr < 0x6000:
+		m.ramBank = v & 0x0F
+	case addr <= cartROMEnd:
+		// Writing 0 and then 1 latches the clock.
+		if m.latchArmed && v == 0x01 && m.rtc != nil {
+			m.rtc.Latch()
+		}
+		m.latchArmed = v == 0x00
+	default:
+		if !m.ramEnabled {
+			return
+		}
+		if m.ramBank >= 0x08 {
+			if m.rtc != nil && m.ramBank <= 0x0C {
+				m.rtc.Write(m.ramBank, v)
+			}
+			return
+		}
+		if len(m.ram) == 0 {
+			return
+		}
+		m.ram[m.ramAddr(addr)] = v
+	}
+}
+
+// Save writes the content of the RAM followed,
+// when the cartridge has one, by the state of the clock.
+func (m *MBC3) Save(w io.Writer) error {
+	if err := saveRAM(w, m.ram); err != nil {
+		return err
+	}
+	if m.rtc == nil {
+		return nil
+	}
+	return m.rtc.Save(w)
+}
+
+// Load reads the content of the RAM and the state of the clock
+// as written by Save. A missing clock state is not an error
+// as other emulators might not write it.
+func (m *MBC3) Load(r io.Reader) error {
+	if err := loadRAM(r, m.ram); err != nil {
+		return err
+	}
+	if m.rtc == nil {
+		return nil
+	}
+	if err := m.rtc.Load(r); err != nil && err != ErrInvalidRTCFooter {
+		return err
+	}
+	return nil
+}
+
+func (m *MBC3) romAddr(addr uint16) int {
+	if addr < romBankSize {
+		return int(addr)
+	}
+	return (int(m.romBank)*romBankSize + int(addr-romBankSize)) % len(m.rom)
+}
+
+func (m *MBC3) ramAddr(addr uint16) int {
+	return (int(m.ramBank)*ramBankSize + int(addr-cartRAMStart)) % len(m.ram)
+}
@@ -0,0 +1,98 @@
+package memory_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/andreaperizzato/gameboy/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMBC3_ROMBanks(t *testing.T) {
+	m := memory.NewMBC3(newBankedROM(128), 0, nil)
+
+	assert.Equal(t, uint8(0x00), m.Read(0x0000), "bank 0")
+	assert.Equal(t, uint8(0x01), m.Read(0x4000), "bank 1 by default")
+
+	m.Write(0x2000, 0x7F)
+	assert.Equal(t, uint8(0x7F), m.Read(0x4000), "bank 0x7F")
+	m.Write(0x2000, 0x00)
+	assert.Equal(t, uint8(0x01), m.Read(0x4000), "bank 0 -> 1")
+}
+
+func TestMBC3_RAMBanks(t *testing.T) {
+	m := memory.NewMBC3(newBankedROM(4), 32*1024, nil)
+	m.Write(0xA000, 0x11)
+	assert.Equal(t, uint8(0xFF), m.Read(0xA000), "disabled")
+
+	m.Write(0x0000, 0x0A)
+	m.Write(0x4000, 0x00)
+	m.Write(0xA000, 0x11)
+	m.Write(0x4000, 0x03)
+	m.Write(0xA000, 0x33)
+	assert.Equal(t, uint8(0x33), m.Read(0xA000), "bank 3")
+	m.Write(0x4000, 0x00)
+	assert.Equal(t, uint8(0x11), m.Read(0xA000), "bank 0")
+
+	// Without an RTC, its registers read 0xFF.
+	m.Write(0x4000, 0x08)
+	assert.Equal(t, uint8(0xFF), m.Read(0xA000), "RTC")
+}
+
+func TestMBC3_RTC(t *testing.T) {
+	rtc := memory.NewRTC(time.Now)
+	m := memory.NewMBC3(newBankedROM(4), 0, rtc)
+	m.Write(0x0000, 0x0A)
+
+	// Set the minutes.
+	m.Write(0x4000, 0x09)
+	m.Write(0xA000, 42)
+	assert.Equal(t, uint8(42), m.Read(0xA000), "minutes")
+
+	// The clock advances but reads don't change until latched.
+	for i := 0; i < 2*4194304; i++ {
+		m.Tick()
+	}
+	m.Write(0x4000, 0x08)
+	assert.Equal(t, uint8(0), m.Read(0xA000), "seconds before latching")
+	m.Write(0x6000, 0x01)
+	assert.Equal(t, uint8(0), m.Read(0xA000), "writing 1 alone doesn't latch")
+	m.Write(0x6000, 0x00)
+	m.Write(0x6000, 0x01)
+	assert.Equal(t, uint8(2), m.Read(0xA000), "seconds after latching")
+}
+
+func TestMBC3_SaveLoad(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	clock := func() time.Time { return now }
+
+	m := memory.NewMBC3(newBankedROM(4), 8*1024, memory.NewRTC(clock))
+	m.Write(0x0000, 0x0A)
+	m.Write(0xA123, 0xAB)
+	m.Write(0x4000, 0x0A)
+	m.Write(0xA000, 5)
+
+	var buf bytes.Buffer
+	assert.NoError(t, m.Save(&buf))
+	assert.Len(t, buf.Bytes(), 8*1024+48)
+
+	now = now.Add(2 * time.Hour)
+	loaded := memory.NewMBC3(newBankedROM(4), 8*1024, memory.NewRTC(clock))
+	assert.NoError(t, loaded.Load(&buf))
+	loaded.Write(0x0000, 0x0A)
+	assert.Equal(t, uint8(0xAB), loaded.Read(0xA123), "RAM")
+	loaded.Write(0x6000, 0x00)
+	loaded.Write(0x6000, 0x01)
+	loaded.Write(0x4000, 0x0A)
+	assert.Equal(t, uint8(7), loaded.Read(0xA000), "hours")
+}
+
+func TestMBC3_LoadWithoutRTCFooter(t *testing.T) {
+	m := memory.NewMBC3(newBankedROM(4), 8*1024, memory.NewRTC(time.Now))
+	ram := make([]uint8, 8*1024)
+	ram[0] = 0xAB
+	assert.NoError(t, m.Load(bytes.NewReader(ram)))
+	m.Write(0x0000, 0x0A)
+	assert.Equal(t, uint8(0xAB), m.Read(0xA000))
+}
@@ -0,0 +1,131 @@
+package memory
+
+import "io"
+
+// More info about MBC5 can be found here:
+// https://gbdev.io/pandocs/#mbc5
+
+// rumbleMotor is the bit of the RAM bank register that
+// controls the motor in rumble cartridges.
+const rumbleMotor = uint8(1 << 3)
+
+// MBC5 is the Memory Bank Controller 5. It's an address space
+// mapping the cartridge ROM and RAM, with their bank registers.
+type MBC5 struct {
+	rom []uint8
+	ram []uint8
+
+	ramEnabled bool
+	// romBank is the 9-bit ROM bank mapped at 0x4000-0x7FFF.
+	// Unlike other MBCs, bank 0 can be mapped there too.
+	romBank uint16
+	ramBank uint8
+
+	// rumble is called when the motor is turned on or off,
+	// it's nil when the cartridge has no motor.
+	rumble  func(on bool)
+	motorOn bool
+}
+
+// NewMBC5 creates a new MBC5 with the given ROM and the given size of RAM.
+// For cartridges with a rumble motor, rumble is called whenever it's turned
+// on or off, otherwise it must be nil.
+func NewMBC5(rom []uint8, ramSize int, rumble func(on bool)) *MBC5 {
+	return &MBC5{
+		rom:     rom,
+		ram:     make([]uint8, ramSize),
+		romBank: 1,
+		rumble:  rumble,
+	}
+}
+
+// Contains returns true when the address is part of the address space.
+func (m *MBC5) Contains(addr uint16) bool {
+	return addr <= cartROMEnd || (addr >= cartRAMStart && addr <= cartRAMEnd)
+}
+
+// Read returns the byte at the given address.
+func (m *MBC5) Read(addr uint16) uint8 {
+	if addr >= cartRAMStart && !m.ramEnabled {
+		return 0xFF
+	}
+	return m.Peek(addr)
+}
+
+// Peek returns the byte mapped at the given address,
+// even when RAM is disabled.
+func (m *MBC5) Peek(addr uint16) uint8 {
+	if addr <= cartROMEnd {
+		return m.rom[m.romAddr(addr)]
+	}
+	if len(m.ram) == 0 {
+		return 0xFF
+	}
+	return m.ram[m.ramAddr(addr)]
+}
+
+// Poke changes the byte mapped at the given address,
+// without changing the bank registers.
+func (m *MBC5) Poke(addr uint16, v uint8) {
+	if addr <= cartROMEnd {
+		m.rom[m.romAddr(addr)] = v
+		return
+	}
+	if len(m.ram) > 0 {
+		m.ram[m.ramAddr(addr)] = v
+	}
+}
+
+// Write writes a value at the given address.
+// Writes to the ROM area set the bank registers.
+func (m *MBC5) Write(addr uint16, v uint8) {
+	switch {
+	case addr < 0x2000:
+		m.ramEnabled = v&0x0F == ramEnableValue
+	case addr < 0x3000:
+		m.romBank = m.romBank&0x100 | uint16(v)
+	case addr < 0x4000:
+		m.romBank = m.romBank&0xFF | uint16(v&0x01)<<8
+	case addr < 0x6000:
+		if m.rumble == nil {
+			m.ramBank = v & 0x0F
+			return
+		}
+		// In rumble cartridges, bit 3 drives the motor
+		// and can't be used to select RAM banks.
+		m.ramBank = v & 0x07
+		on := v&rumbleMotor != 0
+		if on != m.motorOn {
+			m.motorOn = on
+			m.rumble(on)
+		}
+	case addr <= cartROMEnd:
+		// Nothing is mapped here.
+	default:
+		if !m.ramEnabled || len(m.ram) == 0 {
+			return
+		}
+		m.ram[m.ramAddr(addr)] = v
+	}
+}
+
+func (m *MBC5) romAddr(addr uint16) int {
+	if addr < romBankSize {
+		return int(addr)
+	}
+	return (int(m.romBank)*romBankSize + int(addr-romBankSize)) % len(m.rom)
+}
+
+func (m *MBC5) ramAddr(addr uint16) int {
+	return (int(m.ramBank)*ramBankSize + int(addr-cartRAMStart)) % len(m.ram)
+}
+
+// Save writes the content of the RAM.
+func (m *MBC5) Save(w io.Writer) error {
+	return saveRAM(w, m.ram)
+}
+
+// Load reads the content of the RAM as written by Save.
+func (m *MBC5) Load(r io.Reader) error {
+	return loadRAM(r, m.ram)
+}
@@ -0,0 +1,56 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/andreaperizzato/gameboy/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMBC5_ROMBanks(t *testing.T) {
+	rom := newBankedROM(512)
+	// Mark the high banks so they differ from the low ones.
+	rom[0x1FF*0x4000] = 0xAB
+	m := memory.NewMBC5(rom, 0, nil)
+
+	assert.Equal(t, uint8(0x01), m.Read(0x4000), "bank 1 by default")
+	m.Write(0x2000, 0x00)
+	assert.Equal(t, uint8(0x00), m.Read(0x4001), "bank 0 can be mapped")
+
+	m.Write(0x2000, 0xFF)
+	m.Write(0x3000, 0x01)
+	assert.Equal(t, uint8(0xAB), m.Read(0x4000), "bank 0x1FF")
+	m.Write(0x3000, 0x00)
+	assert.Equal(t, uint8(0xFF), m.Read(0x4001), "bank 0xFF")
+}
+
+func TestMBC5_RAMBanks(t *testing.T) {
+	m := memory.NewMBC5(newBankedROM(4), 128*1024, nil)
+	m.Write(0x0000, 0x0A)
+	for bank := uint8(0); bank < 16; bank++ {
+		m.Write(0x4000, bank)
+		m.Write(0xA000, bank+0x10)
+	}
+	for bank := uint8(0); bank < 16; bank++ {
+		m.Write(0x4000, bank)
+		assert.Equal(t, bank+0x10, m.Read(0xA000), "bank %d", bank)
+	}
+	m.Write(0x0000, 0x00)
+	assert.Equal(t, uint8(0xFF), m.Read(0xA000), "disabled")
+}
+
+func TestMBC5_Rumble(t *testing.T) {
+	var calls []bool
+	m := memory.NewMBC5(newBankedROM(4), 32*1024, func(on bool) {
+		calls = append(calls, on)
+	})
+	m.Write(0x0000, 0x0A)
+
+	m.Write(0x4000, 0x09)
+	m.Write(0xA000, 0x11)
+	m.Write(0x4000, 0x0B)
+	m.Write(0x4000, 0x01)
+	assert.Equal(t, []bool{true, false}, calls, "motor changes")
+	// Bit 3 doesn't select the RAM bank.
+	assert.Equal(t, uint8(0x11), m.Read(0xA000), "RAM bank 1")
+}
@@ -7,6 +7,35 @@ type AddressSpace interface {
 	Write(addr uint16, v uint8)
 }
 
+// DebugAddressSpace is an address space which can be accessed
+// without side effects, e.g. by debuggers and memory viewers.
+type DebugAddressSpace interface {
+	AddressSpace
+	// Peek returns the byte stored at the given address.
+	Peek(addr uint16) uint8
+	// Poke changes the byte stored at the given address.
+	Poke(addr uint16, v uint8)
+}
+
+// Peek returns the byte stored at the given address without side effects.
+// Address spaces which don't implement DebugAddressSpace are read normally.
+func Peek(s AddressSpace, addr uint16) uint8 {
+	if d, ok := s.(DebugAddressSpace); ok {
+		return d.Peek(addr)
+	}
+	return s.Read(addr)
+}
+
+// Poke changes the byte stored at the given address without side effects.
+// Address spaces which don't implement DebugAddressSpace are written normally.
+func Poke(s AddressSpace, addr uint16, v uint8) {
+	if d, ok := s.(DebugAddressSpace); ok {
+		d.Poke(addr, v)
+		return
+	}
+	s.Write(addr, v)
+}
+
 // Register is a special byte in memory.
 type Register struct {
 	Address uint16
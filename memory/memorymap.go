@@ -0,0 +1,149 @@
+package memory
+
+import "github.com/andreaperizzato/gameboy/model"
+
+// More info about the memory map can be found here:
+// https://gbdev.io/pandocs/#memory-map
+
+// Mirror is an address space repeating the content of another one,
+// like the echo RAM at 0xE000-0xFDFF which mirrors 0xC000-0xDDFF.
+type Mirror struct {
+	target AddressSpace
+	start  uint16
+	end    uint16
+	offset uint16
+}
+
+// NewMirror creates a new mirror of the target for addresses in [start, end].
+// Each address is mapped to the target by subtracting the offset.
+func NewMirror(target AddressSpace, start, end, offset uint16) *Mirror {
+	return &Mirror{
+		target: target,
+		start:  start,
+		end:    end,
+		offset: offset,
+	}
+}
+
+// Contains returns true when the address is part of the address space.
+func (m *Mirror) Contains(addr uint16) bool {
+	return addr >= m.start && addr <= m.end
+}
+
+// Read returns the byte at the given address.
+func (m *Mirror) Read(addr uint16) uint8 {
+	return m.target.Read(addr - m.offset)
+}
+
+// Write writes a value at the given address.
+func (m *Mirror) Write(addr uint16, v uint8) {
+	m.target.Write(addr-m.offset, v)
+}
+
+// Peek returns the byte stored at the given address.
+func (m *Mirror) Peek(addr uint16) uint8 {
+	return Peek(m.target, addr-m.offset)
+}
+
+// Poke changes the byte stored at the given address.
+func (m *Mirror) Poke(addr uint16, v uint8) {
+	Poke(m.target, addr-m.offset, v)
+}
+
+// Unusable is the prohibited region at 0xFEA0-0xFEFF.
+// On the DMG, writes are ignored and reads return 0x00,
+// or 0xFF while the PPU is blocking access to OAM.
+type Unusable struct {
+	// OAMBlocked, when set, returns true while the PPU is
+	// reading OAM and the CPU can't access it.
+	OAMBlocked func() bool
+}
+
+// Contains returns true when the address is part of the address space.
+func (u *Unusable) Contains(addr uint16) bool {
+	return addr >= unusableStart && addr <= unusableEnd
+}
+
+// Read returns the byte at the given address.
+func (u *Unusable) Read(addr uint16) uint8 {
+	if u.OAMBlocked != nil && u.OAMBlocked() {
+		return 0xFF
+	}
+	return 0x00
+}
+
+// Write is a no-op.
+func (u *Unusable) Write(addr uint16, v uint8) {
+}
+
+// Boundaries of the regions in the memory map.
+const (
+	vramStart     = uint16(0x8000)
+	vramSize      = uint16(0x2000)
+	wramStart     = uint16(0xC000)
+	wramSize      = uint16(0x2000)
+	echoStart     = uint16(0xE000)
+	echoEnd       = uint16(0xFDFF)
+	oamStart      = uint16(0xFE00)
+	oamSize       = uint16(0xA0)
+	unusableStart = uint16(0xFEA0)
+	unusableEnd   = uint16(0xFEFF)
+	ioStart       = uint16(0xFF00)
+	ioSize        = uint16(0x80)
+	hramStart     = uint16(0xFF80)
+	hramSize      = uint16(0x7F)
+)
+
+// DMGMap holds the regions of the DMG memory map.
+// The IE register at 0xFFFF isn't part of it,
+// as the interrupt controller maps it.
+type DMGMap struct {
+	// Cartridge maps 0x0000-0x7FFF and 0xA000-0xBFFF.
+	Cartridge AddressSpace
+	// VRAM is the video RAM at 0x8000-0x9FFF.
+	VRAM *RAM
+	// WRAM is the work RAM at 0xC000-0xDFFF.
+	WRAM *RAM
+	// Echo mirrors 0xC000-0xDDFF at 0xE000-0xFDFF.
+	Echo *Mirror
+	// OAM is the object attribute memory at 0xFE00-0xFE9F.
+	OAM *RAM
+	// Unusable is the prohibited region at 0xFEA0-0xFEFF.
+	Unusable *Unusable
+	// IO holds the IO registers at 0xFF00-0xFF7F.
+	IO *IO
+	// HRAM is the high RAM at 0xFF80-0xFFFE.
+	HRAM *RAM
+}
+
+// NewDMGMap creates the regions of the DMG memory map around the given cartridge,
+// with the IO registers of the model.
+func NewDMGMap(m model.Model, cart AddressSpace) *DMGMap {
+	wram := NewRAM(wramSize, wramStart)
+	return &DMGMap{
+		Cartridge: cart,
+		VRAM:      NewRAM(vramSize, vramStart),
+		WRAM:      wram,
+		Echo:      NewMirror(wram, echoStart, echoEnd, echoStart-wramStart),
+		OAM:       NewRAM(oamSize, oamStart),
+		Unusable:  &Unusable{},
+		IO:        NewIO(m),
+		HRAM:      NewRAM(hramSize, hramStart),
+	}
+}
+
+// Spaces returns the regions to pass to NewMMU.
+// Components mapping their own registers, such as the timer,
+// must be passed to NewMMU before these.
+func (m *DMGMap) Spaces() []AddressSpace {
+	return []AddressSpace{
+		m.Cartridge,
+		m.VRAM,
+		m.WRAM,
+		m.Echo,
+		m.OAM,
+		m.Unusable,
+		m.IO,
+		m.HRAM,
+	}
+}
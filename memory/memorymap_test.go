@@ -0,0 +1,86 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/andreaperizzato/gameboy/interrupts"
+	"github.com/andreaperizzato/gameboy/memory"
+	"github.com/andreaperizzato/gameboy/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDMGMap_Regions(t *testing.T) {
+	m := memory.NewDMGMap(model.DMG, memory.NewROM(make([]uint8, 0x8000), 0))
+	tests := []struct {
+		name  string
+		space memory.AddressSpace
+		first uint16
+		last  uint16
+	}{
+		{"VRAM", m.VRAM, 0x8000, 0x9FFF},
+		{"WRAM", m.WRAM, 0xC000, 0xDFFF},
+		{"Echo", m.Echo, 0xE000, 0xFDFF},
+		{"OAM", m.OAM, 0xFE00, 0xFE9F},
+		{"Unusable", m.Unusable, 0xFEA0, 0xFEFF},
+		{"IO", m.IO, 0xFF00, 0xFF7F},
+		{"HRAM", m.HRAM, 0xFF80, 0xFFFE},
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			assert.False(t, tC.space.Contains(tC.first-1), "before")
+			assert.True(t, tC.space.Contains(tC.first), "first")
+			assert.True(t, tC.space.Contains(tC.last), "last")
+			assert.False(t, tC.space.Contains(tC.last+1), "after")
+		})
+	}
+}
+
+func TestDMGMap_MMU(t *testing.T) {
+	rom := make([]uint8, 0x8000)
+	rom[0x1234] = 0xAB
+	m := memory.NewDMGMap(model.DMG, memory.NewROM(rom, 0))
+	mmu := memory.NewMMU(memory.NewROM(nil, 0), m.Spaces()...)
+
+	assert.Equal(t, uint8(0xAB), mmu.Read(0x1234), "cartridge")
+	mmu.Write(0x1234, 0x00)
+	assert.Equal(t, uint8(0xAB), mmu.Read(0x1234), "ROM is read only")
+
+	// Every address is mapped, except the cartridge RAM which the ROM doesn't have
+	// and IE which belongs to the interrupt controller.
+	assert.Equal(t, uint8(0xFF), mmu.Read(0xA000), "no cartridge RAM")
+	for _, addr := range []uint16{0x8000, 0xC000, 0xFE00, 0xFF80} {
+		mmu.Write(addr, 0x42)
+		assert.Equal(t, uint8(0x42), mmu.Read(addr), "0x%04X", addr)
+	}
+	assert.False(t, mmu.Contains(0xFFFF), "IE")
+}
+
+func TestDMGMap_InterruptEnable(t *testing.T) {
+	// The interrupt controller owns IE wherever it's passed to the MMU.
+	irq := interrupts.New()
+	m := memory.NewDMGMap(model.DMG, memory.NewROM(nil, 0))
+	mmu := memory.NewMMU(memory.NewROM(nil, 0), append(m.Spaces(), irq)...)
+
+	mmu.Write(0xFFFF, 0x1F)
+	assert.Equal(t, uint8(0x1F), irq.Read(0xFFFF), "IE")
+}
+
+func TestDMGMap_Echo(t *testing.T) {
+	m := memory.NewDMGMap(model.DMG, memory.NewROM(nil, 0))
+	mmu := memory.NewMMU(memory.NewROM(nil, 0), m.Spaces()...)
+
+	mmu.Write(0xC123, 0x11)
+	assert.Equal(t, uint8(0x11), mmu.Read(0xE123), "read echo")
+	mmu.Write(0xFDFF, 0x22)
+	assert.Equal(t, uint8(0x22), mmu.Read(0xDDFF), "write echo")
+}
+
+func TestDMGMap_Unusable(t *testing.T) {
+	m := memory.NewDMGMap(model.DMG, memory.NewROM(nil, 0))
+	m.Unusable.Write(0xFEA0, 0x11)
+	assert.Equal(t, uint8(0x00), m.Unusable.Read(0xFEA0))
+
+	blocked := true
+	m.Unusable.OAMBlocked = func() bool { return blocked }
+	assert.Equal(t, uint8(0xFF), m.Unusable.Read(0xFEA0))
+}
@@ -2,25 +2,94 @@ package memory
 
 const bootstrapCompletedAddr = uint16(0xFF50)
 
-// MMU manages access to the memory
+// pageSize is the number of addresses in a page of the decoding table.
+const pageSize = 0x100
+
+// page maps the addresses of a page to their address space.
+type page [pageSize]AddressSpace
+
+// MMU manages access to the memory.
+// Addresses are decoded through a table of 256 pages: a page entirely
+// served by one address space points to it directly, the others have
+// one entry per address.
 type MMU struct {
-	spaces []AddressSpace
+	boot        AddressSpace
+	bootEnabled bool
+	spaces      []AddressSpace
+	pages       [0x100]AddressSpace
+	mixed       [0x100]*page
 }
 
 // NewMMU creates a new MMU.
-func NewMMU(boot *ROM, spaces ...AddressSpace) *MMU {
-	return &MMU{
-		spaces: append([]AddressSpace{boot}, spaces...),
+// When address spaces overlap, the first one containing an address handles it.
+func NewMMU(boot AddressSpace, spaces ...AddressSpace) *MMU {
+	c := &MMU{
+		boot:        boot,
+		bootEnabled: true,
+		spaces:      spaces,
 	}
+	c.Remap()
+	return c
 }
 
-func (c *MMU) spaceForAddr(addr uint16) AddressSpace {
-	for _, s := range c.spaces {
+// Remap rebuilds the decoding table.
+// It must be called when an address space changes the addresses it contains.
+// Bank switches don't need it, as the banks are selected inside the MBC.
+func (c *MMU) Remap() {
+	for p := range c.pages {
+		c.mapPage(uint8(p))
+	}
+}
+
+func (c *MMU) mapPage(p uint8) {
+	start := uint16(p) << 8
+	first := c.lookup(start)
+	single := true
+	for i := 1; i < pageSize && single; i++ {
+		single = c.lookup(start+uint16(i)) == first
+	}
+	if single {
+		c.pages[p], c.mixed[p] = c.space(first), nil
+		return
+	}
+	m := &page{}
+	for i := range m {
+		m[i] = c.space(c.lookup(start + uint16(i)))
+	}
+	c.pages[p], c.mixed[p] = nil, m
+}
+
+// lookup returns the index of the first address space containing the address,
+// where -1 is the boot ROM and len(c.spaces) means no address space.
+func (c *MMU) lookup(addr uint16) int {
+	if c.bootEnabled && c.boot.Contains(addr) {
+		return -1
+	}
+	for i, s := range c.spaces {
 		if s.Contains(addr) {
-			return s
+			return i
 		}
 	}
-	return nil
+	return len(c.spaces)
+}
+
+func (c *MMU) space(i int) AddressSpace {
+	switch {
+	case i < 0:
+		return c.boot
+	case i < len(c.spaces):
+		return c.spaces[i]
+	default:
+		return nil
+	}
+}
+
+func (c *MMU) spaceForAddr(addr uint16) AddressSpace {
+	p := addr >> 8
+	if m := c.mixed[p]; m != nil {
+		return m[addr&0xFF]
+	}
+	return c.pages[p]
 }
 
 // Contains returns true when the address is part of the address space.
@@ -29,7 +98,7 @@ func (c *MMU) Contains(addr uint16) bool {
 }
 
 // Read returns the byte at the given address.
-// Panics when the address is not in the address space.
+// Unmapped addresses read 0xFF.
 func (c *MMU) Read(addr uint16) uint8 {
 	if s := c.spaceForAddr(addr); s != nil {
 		return s.Read(addr)
@@ -38,9 +107,9 @@ func (c *MMU) Read(addr uint16) uint8 {
 }
 
 // Write writes a value at the given address.
-// Panics when the address is not in the address space.
+// Writes to unmapped addresses are ignored.
 func (c *MMU) Write(addr uint16, v uint8) {
-	if addr == bootstrapCompletedAddr {
+	if addr == bootstrapCompletedAddr && c.bootEnabled {
 		c.disableBootRom()
 	}
 	if s := c.spaceForAddr(addr); s != nil {
@@ -48,7 +117,24 @@ func (c *MMU) Write(addr uint16, v uint8) {
 	}
 }
 
+// Peek returns the byte stored at the given address without side effects.
+// Unmapped addresses read 0xFF.
+func (c *MMU) Peek(addr uint16) uint8 {
+	if s := c.spaceForAddr(addr); s != nil {
+		return Peek(s, addr)
+	}
+	return 0xFF
+}
+
+// Poke changes the byte stored at the given address without side effects,
+// e.g. poking 0xFF50 doesn't disable the boot ROM.
+func (c *MMU) Poke(addr uint16, v uint8) {
+	if s := c.spaceForAddr(addr); s != nil {
+		Poke(s, addr, v)
+	}
+}
+
 func (c *MMU) disableBootRom() {
-	// the boot rom is the first address space.
-	c.spaces = c.spaces[1:]
+	c.bootEnabled = false
+	c.Remap()
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/andreaperizzato/gameboy/memory"
+	"github.com/andreaperizzato/gameboy/model"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -69,3 +70,41 @@ func TestMMU_DisablingBootROM(t *testing.T) {
 		assert.Equal(t, expBytes[i], m.Read(i))
 	}
 }
+
+func BenchmarkMMU_Read(b *testing.B) {
+	m := memory.NewMMU(memory.NewDMGBootROM(), memory.NewDMGMap(model.DMG, memory.NewROM(make([]uint8, 0x8000), 0)).Spaces()...)
+	for i := 0; i < b.N; i++ {
+		m.Read(uint16(i))
+	}
+}
+
+func TestMMU_PeekPoke(t *testing.T) {
+	boot := memory.NewROM([]uint8{0xAA}, 0)
+	m := memory.NewDMGMap(model.DMG, memory.NewMBC1(newBankedROM(4), 0x2000))
+	mmu := memory.NewMMU(boot, m.Spaces()...)
+
+	// Poking doesn't disable the boot ROM.
+	mmu.Poke(0xFF50, 0x01)
+	assert.Equal(t, uint8(0xAA), mmu.Peek(0x0000), "boot ROM")
+
+	// Poking the ROM patches it rather than switching banks.
+	mmu.Poke(0x2000, 0x42)
+	assert.Equal(t, uint8(0x42), mmu.Peek(0x2000), "patched ROM")
+	assert.Equal(t, uint8(0x01), mmu.Peek(0x4000), "bank 1 still mapped")
+
+	// Cartridge RAM is accessible even when disabled.
+	mmu.Poke(0xA000, 0x12)
+	assert.Equal(t, uint8(0x12), mmu.Peek(0xA000), "peek disabled RAM")
+	assert.Equal(t, uint8(0xFF), mmu.Read(0xA000), "read disabled RAM")
+
+	// IO registers are accessed without masks.
+	mmu.Poke(0xFF44, 0x90)
+	assert.Equal(t, uint8(0x90), mmu.Peek(0xFF44), "LY")
+	mmu.Poke(0xFF13, 0x34)
+	assert.Equal(t, uint8(0x34), mmu.Peek(0xFF13), "write-only NR13")
+
+	// Other spaces are accessed normally.
+	mmu.Poke(0xE010, 0x56)
+	assert.Equal(t, uint8(0x56), mmu.Peek(0xC010), "echo RAM")
+	assert.Equal(t, uint8(0x00), mmu.Peek(0xFEA0), "unusable")
+}
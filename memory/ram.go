@@ -19,7 +19,7 @@ func NewRAM(size uint16, offset uint16) *RAM {
 
 // Contains returns true when the address is part of the address space.
 func (r *RAM) Contains(addr uint16) bool {
-	return addr >= r.offset && addr < r.offset+uint16(len(r.bytes))
+	return addr >= r.offset && int(addr-r.offset) < len(r.bytes)
 }
 
 func (r *RAM) validateAddress(addr uint16) {
@@ -8,7 +8,7 @@ import (
 )
 
 func TestROM_ReadOnly(t *testing.T) {
-	rom := memory.NewGBCBootROM()
+	rom := memory.NewROM([]uint8{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}, 0)
 	v := rom.Read(0x07)
 	rom.Write(0x07, v+1)
 	assert.Equal(t, v, rom.Read(0x07))
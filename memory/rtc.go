@@ -0,0 +1,203 @@
+package memory
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+// More info about the MBC3 real-time clock can be found here:
+// https://gbdev.io/pandocs/#mbc3
+// https://bgb.bircd.org/rtcsave.html (save format)
+
+const (
+	// The RTC counts seconds from the 32768Hz oscillator in the cartridge,
+	// which is the same as one every 4194304 cycles of the system clock.
+	cyclesPerSecond = 4194304
+
+	// rtcFooterSize is the size of the RTC state appended to the
+	// save RAM by other emulators: the 5 registers, the 5 latched
+	// registers as 32-bit little-endian values, and a 64-bit timestamp.
+	// Some emulators write a 32-bit timestamp, hence a shorter footer.
+	rtcFooterSize      = 48
+	rtcShortFooterSize = 44
+
+	secondsPerDay = 24 * 60 * 60
+	// rtcDays is the range of the 9-bit day counter.
+	rtcDays = 512
+
+	rtcDayHigh  = uint8(1 << 0)
+	rtcHalt     = uint8(1 << 6)
+	rtcDayCarry = uint8(1 << 7)
+)
+
+// ErrInvalidRTCFooter is returned when the RTC state can't be loaded.
+var ErrInvalidRTCFooter = errors.New("invalid rtc footer")
+
+// RTC is the real-time clock of MBC3 cartridges.
+// It advances with emulated time and, when saved and loaded back,
+// catches up with the time elapsed in between.
+type RTC struct {
+	now func() time.Time
+
+	// regs are the live registers, latched is the copy the CPU reads.
+	// Both are indexed from register 0x08: S, M, H, DL, DH.
+	regs    [5]uint8
+	latched [5]uint8
+	// cycles counts the clock cycles in the current second.
+	cycles uint32
+}
+
+// RTC registers.
+const (
+	rtcS = iota
+	rtcM
+	rtcH
+	rtcDL
+	rtcDH
+)
+
+// rtcMasks are the bits used by each register.
+var rtcMasks = [5]uint8{0x3F, 0x3F, 0x1F, 0xFF, rtcDayHigh | rtcHalt | rtcDayCarry}
+
+// NewRTC creates a new real-time clock using the given time source
+// to compute the time elapsed between saving and loading.
+func NewRTC(now func() time.Time) *RTC {
+	return &RTC{now: now}
+}
+
+// Tick advances the clock by one cycle.
+func (r *RTC) Tick() {
+	if r.regs[rtcDH]&rtcHalt != 0 {
+		return
+	}
+	r.cycles++
+	if r.cycles == cyclesPerSecond {
+		r.cycles = 0
+		r.advance()
+	}
+}
+
+// Latch copies the live registers into the ones the CPU reads.
+func (r *RTC) Latch() {
+	r.latched = r.regs
+}
+
+// Read returns the latched value of a register (0x08-0x0C).
+func (r *RTC) Read(reg uint8) uint8 {
+	return r.latched[reg-0x08]
+}
+
+// Write sets a register (0x08-0x0C).
+func (r *RTC) Write(reg uint8, v uint8) {
+	i := reg - 0x08
+	if i == rtcS {
+		// Writing the seconds resets the sub-second counter.
+		r.cycles = 0
+	}
+	r.regs[i] = v & rtcMasks[i]
+	r.latched[i] = r.regs[i]
+}
+
+// advance adds one second to the clock.
+// Registers set to invalid values (e.g. 61 seconds) count up
+// to their maximum value and wrap to 0 without carrying.
+func (r *RTC) advance() {
+	r.regs[rtcS] = (r.regs[rtcS] + 1) & rtcMasks[rtcS]
+	if r.regs[rtcS] != 60 {
+		return
+	}
+	r.regs[rtcS] = 0
+	r.regs[rtcM] = (r.regs[rtcM] + 1) & rtcMasks[rtcM]
+	if r.regs[rtcM] != 60 {
+		return
+	}
+	r.regs[rtcM] = 0
+	r.regs[rtcH] = (r.regs[rtcH] + 1) & rtcMasks[rtcH]
+	if r.regs[rtcH] != 24 {
+		return
+	}
+	r.regs[rtcH] = 0
+	r.regs[rtcDL]++
+	if r.regs[rtcDL] != 0 {
+		return
+	}
+	if r.regs[rtcDH]&rtcDayHigh == 0 {
+		r.regs[rtcDH] |= rtcDayHigh
+		return
+	}
+	// The 9-bit day counter overflowed.
+	r.regs[rtcDH] = r.regs[rtcDH]&^rtcDayHigh | rtcDayCarry
+}
+
+// Save writes the state of the clock in the 48-byte footer format.
+func (r *RTC) Save(w io.Writer) error {
+	var footer [rtcFooterSize]uint8
+	for i := 0; i < 5; i++ {
+		binary.LittleEndian.PutUint32(footer[i*4:], uint32(r.regs[i]))
+		binary.LittleEndian.PutUint32(footer[20+i*4:], uint32(r.latched[i]))
+	}
+	binary.LittleEndian.PutUint64(footer[40:], uint64(r.now().Unix()))
+	_, err := w.Write(footer[:])
+	return err
+}
+
+// Load reads the state of the clock in the 48-byte footer format, or the
+// 44-byte one, and advances it by the time elapsed since it was saved.
+func (r *RTC) Load(rd io.Reader) error {
+	var footer [rtcFooterSize]uint8
+	n, err := io.ReadFull(rd, footer[:])
+	var timestamp int64
+	switch {
+	case err == nil:
+		timestamp = int64(binary.LittleEndian.Uint64(footer[40:]))
+	case err == io.ErrUnexpectedEOF && n == rtcShortFooterSize:
+		timestamp = int64(binary.LittleEndian.Uint32(footer[40:]))
+	default:
+		return ErrInvalidRTCFooter
+	}
+	for i := 0; i < 5; i++ {
+		r.regs[i] = uint8(binary.LittleEndian.Uint32(footer[i*4:])) & rtcMasks[i]
+		r.latched[i] = uint8(binary.LittleEndian.Uint32(footer[20+i*4:])) & rtcMasks[i]
+	}
+	r.cycles = 0
+
+	elapsed := r.now().Sub(time.Unix(timestamp, 0))
+	if elapsed <= 0 || r.regs[rtcDH]&rtcHalt != 0 {
+		return nil
+	}
+	r.catchUp(int64(elapsed / time.Second))
+	return nil
+}
+
+// catchUp advances the clock by the given number of seconds.
+func (r *RTC) catchUp(seconds int64) {
+	// Invalid values don't carry, so they're advanced one second
+	// at a time until they wrap, which takes a few hours at most.
+	for ; seconds > 0 && !r.valid(); seconds-- {
+		r.advance()
+	}
+	if seconds == 0 {
+		return
+	}
+	day := int64(r.regs[rtcDL]) | int64(r.regs[rtcDH]&rtcDayHigh)<<8
+	total := int64(r.regs[rtcS]) + int64(r.regs[rtcM])*60 + int64(r.regs[rtcH])*60*60 +
+		day*secondsPerDay + seconds
+
+	r.regs[rtcS] = uint8(total % 60)
+	r.regs[rtcM] = uint8(total / 60 % 60)
+	r.regs[rtcH] = uint8(total / (60 * 60) % 24)
+	day = total / secondsPerDay
+	if day >= rtcDays {
+		r.regs[rtcDH] |= rtcDayCarry
+		day %= rtcDays
+	}
+	r.regs[rtcDL] = uint8(day)
+	r.regs[rtcDH] = r.regs[rtcDH]&^rtcDayHigh | uint8(day>>8)&rtcDayHigh
+}
+
+// valid returns true when the seconds, minutes and hours are in range.
+func (r *RTC) valid() bool {
+	return r.regs[rtcS] < 60 && r.regs[rtcM] < 60 && r.regs[rtcH] < 24
+}
@@ -0,0 +1,162 @@
+package memory_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/andreaperizzato/gameboy/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTC_Tick(t *testing.T) {
+	rtc := memory.NewRTC(time.Now)
+	tickRTC(rtc, 4194304-1)
+	rtc.Latch()
+	assert.Equal(t, uint8(0), rtc.Read(0x08), "seconds before a second")
+
+	tickRTC(rtc, 1)
+	rtc.Latch()
+	assert.Equal(t, uint8(1), rtc.Read(0x08), "seconds after a second")
+}
+
+func TestRTC_Latch(t *testing.T) {
+	rtc := memory.NewRTC(time.Now)
+	tickRTC(rtc, 4194304)
+	assert.Equal(t, uint8(0), rtc.Read(0x08), "not latched")
+	rtc.Latch()
+	assert.Equal(t, uint8(1), rtc.Read(0x08), "latched")
+}
+
+func TestRTC_Carry(t *testing.T) {
+	rtc := memory.NewRTC(time.Now)
+	// 511 days, 23:59:59
+	rtc.Write(0x08, 59)
+	rtc.Write(0x09, 59)
+	rtc.Write(0x0A, 23)
+	rtc.Write(0x0B, 0xFF)
+	rtc.Write(0x0C, 0x01)
+
+	tickRTC(rtc, 4194304)
+	rtc.Latch()
+	for reg := uint8(0x08); reg <= 0x0B; reg++ {
+		assert.Equal(t, uint8(0), rtc.Read(reg), "register 0x%02X", reg)
+	}
+	assert.Equal(t, uint8(0x80), rtc.Read(0x0C), "day carry")
+}
+
+func TestRTC_InvalidValues(t *testing.T) {
+	rtc := memory.NewRTC(time.Now)
+	// Seconds count up to 63 and then wrap to 0 without carrying.
+	rtc.Write(0x08, 63)
+	tickRTC(rtc, 4194304)
+	rtc.Latch()
+	assert.Equal(t, uint8(0), rtc.Read(0x08), "seconds")
+	assert.Equal(t, uint8(0), rtc.Read(0x09), "minutes")
+}
+
+func TestRTC_Halt(t *testing.T) {
+	rtc := memory.NewRTC(time.Now)
+	rtc.Write(0x0C, 0x40)
+	tickRTC(rtc, 4194304)
+	rtc.Latch()
+	assert.Equal(t, uint8(0), rtc.Read(0x08), "seconds")
+	assert.Equal(t, uint8(0x40), rtc.Read(0x0C), "halt")
+}
+
+func TestRTC_SaveLoad(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	clock := func() time.Time { return now }
+
+	rtc := memory.NewRTC(clock)
+	rtc.Write(0x08, 10)
+	rtc.Write(0x09, 20)
+	rtc.Write(0x0A, 3)
+	rtc.Latch()
+	var buf bytes.Buffer
+	assert.NoError(t, rtc.Save(&buf))
+
+	data := buf.Bytes()
+	assert.Len(t, data, 48)
+	assert.Equal(t, uint32(10), binary.LittleEndian.Uint32(data[0:]), "seconds")
+	assert.Equal(t, uint32(20), binary.LittleEndian.Uint32(data[4:]), "minutes")
+	assert.Equal(t, uint32(10), binary.LittleEndian.Uint32(data[20:]), "latched seconds")
+	assert.Equal(t, uint64(1600000000), binary.LittleEndian.Uint64(data[40:]), "timestamp")
+
+	// Load it back a day, an hour and 55 seconds later.
+	now = now.Add(25*time.Hour + 55*time.Second)
+	loaded := memory.NewRTC(clock)
+	assert.NoError(t, loaded.Load(bytes.NewReader(data)))
+	assert.Equal(t, uint8(10), loaded.Read(0x08), "latched seconds are not advanced")
+	loaded.Latch()
+	assert.Equal(t, uint8(5), loaded.Read(0x08), "seconds")
+	assert.Equal(t, uint8(21), loaded.Read(0x09), "minutes")
+	assert.Equal(t, uint8(4), loaded.Read(0x0A), "hours")
+	assert.Equal(t, uint8(1), loaded.Read(0x0B), "days")
+}
+
+func TestRTC_LoadYearsLater(t *testing.T) {
+	saved := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := saved.Add(1000*24*time.Hour + time.Hour + 2*time.Minute + 3*time.Second)
+	rtc := memory.NewRTC(func() time.Time { return now })
+	footer := make([]uint8, 48)
+	binary.LittleEndian.PutUint64(footer[40:], uint64(saved.Unix()))
+
+	start := time.Now()
+	assert.NoError(t, rtc.Load(bytes.NewReader(footer)))
+	assert.Less(t, int64(time.Since(start)), int64(100*time.Millisecond), "duration")
+	rtc.Latch()
+	// 1000 days overflow the 9-bit counter and leave 488 days.
+	assert.Equal(t, uint8(3), rtc.Read(0x08), "seconds")
+	assert.Equal(t, uint8(2), rtc.Read(0x09), "minutes")
+	assert.Equal(t, uint8(1), rtc.Read(0x0A), "hours")
+	assert.Equal(t, uint8(488-256), rtc.Read(0x0B), "days")
+	assert.Equal(t, uint8(0x81), rtc.Read(0x0C), "day high and carry")
+
+	// A footer without timestamp has been saved in 1970.
+	rtc = memory.NewRTC(time.Now)
+	assert.NoError(t, rtc.Load(bytes.NewReader(make([]uint8, 48))))
+	rtc.Latch()
+	assert.Equal(t, uint8(0x80), rtc.Read(0x0C)&0x80, "day carry")
+}
+
+func TestRTC_LoadInvalidValues(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	rtc := memory.NewRTC(func() time.Time { return now })
+	footer := make([]uint8, 48)
+	footer[0] = 62 // seconds
+	footer[4] = 59 // minutes
+	binary.LittleEndian.PutUint64(footer[40:], uint64(now.Unix()-65))
+
+	assert.NoError(t, rtc.Load(bytes.NewReader(footer)))
+	rtc.Latch()
+	// 63 and 0 without carrying, then 63 more seconds.
+	assert.Equal(t, uint8(3), rtc.Read(0x08), "seconds")
+	assert.Equal(t, uint8(0), rtc.Read(0x09), "minutes")
+	assert.Equal(t, uint8(1), rtc.Read(0x0A), "hours")
+}
+
+func TestRTC_LoadShortFooter(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	rtc := memory.NewRTC(func() time.Time { return now })
+	footer := make([]uint8, 44)
+	footer[0] = 10
+	binary.LittleEndian.PutUint32(footer[40:], uint32(now.Unix()-5))
+
+	assert.NoError(t, rtc.Load(bytes.NewReader(footer)))
+	rtc.Latch()
+	assert.Equal(t, uint8(15), rtc.Read(0x08), "seconds")
+}
+
+func TestRTC_LoadInvalid(t *testing.T) {
+	rtc := memory.NewRTC(time.Now)
+	err := rtc.Load(bytes.NewReader(make([]uint8, 10)))
+	assert.Equal(t, memory.ErrInvalidRTCFooter, err)
+}
+
+func tickRTC(rtc *memory.RTC, times int) {
+	for i := 0; i < times; i++ {
+		rtc.Tick()
+	}
+}
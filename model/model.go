@@ -0,0 +1,62 @@
+// Package model lists the Gameboy hardware models, which
+// differ in their boot ROM and in some hardware behaviours.
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Model is a Gameboy hardware model.
+type Model uint8
+
+// All supported models.
+const (
+	// DMG is the original Gameboy.
+	DMG Model = iota
+	// DMG0 is the early revision of the original Gameboy.
+	DMG0
+	// MGB is the Gameboy Pocket.
+	MGB
+	// SGB is the Super Gameboy.
+	SGB
+	// CGB is the Gameboy Color.
+	CGB
+	// AGB is the Gameboy Advance running Gameboy games.
+	AGB
+)
+
+var modelNames = map[Model]string{
+	DMG:  "DMG",
+	DMG0: "DMG0",
+	MGB:  "MGB",
+	SGB:  "SGB",
+	CGB:  "CGB",
+	AGB:  "AGB",
+}
+
+// ErrUnknownModel is returned when parsing an unknown model name.
+var ErrUnknownModel = errors.New("unknown model")
+
+// Parse returns the model with the given name, e.g. "dmg".
+func Parse(name string) (Model, error) {
+	for m, n := range modelNames {
+		if strings.EqualFold(n, name) {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q", ErrUnknownModel, name)
+}
+
+func (m Model) String() string {
+	if name, ok := modelNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("UNKNOWN (%d)", uint8(m))
+}
+
+// IsCGB returns true for the models with Gameboy Color hardware.
+func (m Model) IsCGB() bool {
+	return m == CGB || m == AGB
+}
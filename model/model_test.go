@@ -0,0 +1,26 @@
+package model_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/andreaperizzato/gameboy/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	m, err := model.Parse("cgb")
+	assert.NoError(t, err)
+	assert.Equal(t, model.CGB, m)
+	assert.Equal(t, "CGB", m.String())
+
+	_, err = model.Parse("gba")
+	assert.True(t, errors.Is(err, model.ErrUnknownModel))
+}
+
+func TestModel_IsCGB(t *testing.T) {
+	assert.False(t, model.DMG.IsCGB())
+	assert.False(t, model.SGB.IsCGB())
+	assert.True(t, model.CGB.IsCGB())
+	assert.True(t, model.AGB.IsCGB())
+}
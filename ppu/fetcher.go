@@ -14,6 +14,9 @@ import (
 // 3. Reads the second part of the tile data (second byte)
 // 4. Constructs 8 new pixels and puts them in the fifo queue
 
+// More info about the window can be found here:
+// https://gbdev.io/pandocs/#ff4a-wy-window-y-position-r-w-ff4b-wx-window-x-position-minus-7-r-w
+
 // fetcherState is the state of the fetcher.
 type fetcherState uint8
 
@@ -38,20 +41,38 @@ type Fetcher struct {
 	tileLine  uint8
 	tileIndex uint8
 	tileID    uint8
-	tileData  []uint8
-
-	// BGP - BG Palette Data
-	// https://gbdev.io/pandocs/#ff47-bgp-bg-palette-data-r-w-non-cgb-mode-only
-	bgp memory.Register
+	// tileData holds the color numbers of the pixels of the tile.
+	tileData []uint8
+
+	// WY, WX - Window Y Position, Window X Position minus 7
+	wy memory.Register
+	wx memory.Register
+	// tileDataSelect is LCDC bit 4, which selects the 0x8000 unsigned
+	// tile data addressing instead of the 0x8800 signed one.
+	tileDataSelect memory.RegisterBit
+	// LCDC bits 5 and 6 enable the window and select its map.
+	windowEnabled memory.RegisterBit
+	windowMap     memory.RegisterBit
+	// wyTriggered is true once LY has been equal to WY in the current frame.
+	wyTriggered bool
+	// windowLine is the line of the window drawn next. It only advances
+	// on lines where the window is drawn, not with LY.
+	windowLine uint8
+	// inWindow is true once the window started on the current line.
+	inWindow bool
 }
 
-// NewFetcher creates a new fetcher.
-func NewFetcher(m memory.AddressSpace) *Fetcher {
+// NewFetcher creates a new fetcher reading tiles from m and registers from regs.
+func NewFetcher(m, regs memory.AddressSpace) *Fetcher {
 	return &Fetcher{
-		mem:      m,
-		Q:        NewFIFOQueue(16),
-		tileData: make([]uint8, 8),
-		bgp:      memory.NewRegister(m, 0xFF47),
+		mem:            m,
+		Q:              NewFIFOQueue(16),
+		tileData:       make([]uint8, 8),
+		tileDataSelect: memory.NewRegisterBit(regs, 0xFF40, 4),
+		wy:             memory.NewRegister(regs, 0xFF4A),
+		wx:             memory.NewRegister(regs, 0xFF4B),
+		windowEnabled:  memory.NewRegisterBit(regs, 0xFF40, 5),
+		windowMap:      memory.NewRegisterBit(regs, 0xFF40, 6),
 	}
 }
 
@@ -66,7 +87,8 @@ func (f *Fetcher) Tick() {
 
 	switch f.state {
 	case readTileID:
-		f.tileID = f.mem.Read(f.mapAddr + uint16(f.tileIndex))
+		// Rows of the map are 32 tiles wide and wrap around.
+		f.tileID = f.mem.Read(f.mapAddr + uint16(f.tileIndex%32))
 		f.state = readTileData0
 
 	case readTileData0:
@@ -91,23 +113,70 @@ func (f *Fetcher) Tick() {
 	}
 }
 
-// Start fetching a line of pixels starting from the given tile address in the
-// background map. Here, tileLine indicates which row of pixels to pick from
-// each tile we read.
-func (f *Fetcher) Start(mapAddr uint16, tileLine uint8) {
-	f.tileIndex = 0
+// Start fetching a line of pixels starting from the given row address in the
+// background map and the given tile in that row. Here, tileLine indicates
+// which row of pixels to pick from each tile we read.
+func (f *Fetcher) Start(mapAddr uint16, tileIndex, tileLine uint8) {
+	f.tileIndex = tileIndex
 	f.mapAddr = mapAddr
 	f.tileLine = tileLine
 	f.state = readTileID
+	f.inWindow = false
 
 	f.Q.Clear()
 }
 
+// LatchWindow must be called at the start of each line, before Start.
+// WY is only compared with LY there: once they're equal, the window
+// can be drawn on all the following lines of the frame.
+func (f *Fetcher) LatchWindow(ly uint8) {
+	if ly == 0 {
+		f.wyTriggered = false
+		f.windowLine = 0
+	}
+	if ly == f.wy.Get() {
+		f.wyTriggered = true
+	}
+}
+
+// StartWindow restarts fetching from the window map when the window begins
+// at pixel x of the current line, and returns true when it does so.
+// The pixels of the background still in the queue are discarded.
+// When WX is less than 7, the window starts at the left edge of the
+// screen and skip is the number of its pixels to drop.
+func (f *Fetcher) StartWindow(x uint8) (skip uint8, started bool) {
+	if f.inWindow || !f.wyTriggered || !f.windowEnabled.Get() {
+		return 0, false
+	}
+	// WX is the position of the window plus 7.
+	wx := f.wx.Get()
+	if int(x)+7 < int(wx) {
+		return 0, false
+	}
+	if wx < 7 {
+		skip = 7 - wx
+	}
+	mapAddr := uint16(0x9800)
+	if f.windowMap.Get() {
+		mapAddr = 0x9C00
+	}
+	f.Start(mapAddr+uint16(f.windowLine/8)*32, 0, f.windowLine%8)
+	f.inWindow = true
+	f.windowLine++
+	f.ticks = 0
+	return skip, true
+}
+
 func (f *Fetcher) readTileData(bitPlane uint8, nextState fetcherState) {
 	// A tile's graphical data takes 16 bytes (2B per row of 8px).
 	// Tile data starts at address 0x8000 so we first compute an offset
 	// to find out where the data for the tile we want starts.
 	offset := 0x8000 + uint16(f.tileID)*16
+	if !f.tileDataSelect.Get() {
+		// In the 0x8800 addressing mode, the tile id is signed
+		// and tile 0 is at 0x9000.
+		offset = uint16(0x9000 + int(int8(f.tileID))*16)
+	}
 	// Then, from that starting offset, we compute the final address
 	// to read by finding out which of the 8px (ie 2B) rows of the tile we want.
 	addr := offset + uint16(f.tileLine)*2
@@ -119,17 +188,7 @@ func (f *Fetcher) readTileData(bitPlane uint8, nextState fetcherState) {
 			f.tileData[bitPos] = (data >> bitPos) & 1
 		} else {
 			f.tileData[bitPos] |= ((data >> bitPos) & 1) << 1
-			f.tileData[bitPos] = f.mapColor(f.tileData[bitPos])
 		}
 	}
 	f.state = nextState
 }
-
-func (f *Fetcher) mapColor(col uint8) uint8 {
-	bgp := f.bgp.Get()
-	// BGP is 0bAABBCCDD
-	// where the nth pair of bits is the shade for the nth color.
-	// E.g. BGP=0b10110001 and col=0x03 then the shade is 0b10:
-	// (0b10110001 >> 6) & 0x00000011 = 0b00000010 & 0x00000011 = 0b10
-	return bgp >> (col * 2) & 0x03
-}
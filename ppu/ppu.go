@@ -1,18 +1,31 @@
 package ppu
 
 import (
+	"github.com/andreaperizzato/gameboy/interrupts"
 	"github.com/andreaperizzato/gameboy/memory"
+	"github.com/andreaperizzato/gameboy/model"
 )
 
 // ppuState is a state the PPU can be in.
+// Its value is the mode reported in the STAT register.
 type ppuState uint8
 
 // All Possible PPU States.
 const (
-	oamSearch ppuState = iota
-	pixelTransfer
-	hBlank
-	vBlank
+	hBlank        ppuState = 0
+	vBlank        ppuState = 1
+	oamSearch     ppuState = 2
+	pixelTransfer ppuState = 3
+)
+
+// Bits of the STAT register.
+const (
+	statMode        = uint8(0x03)
+	statCoincidence = uint8(1 << 2)
+	statHBlankIRQ   = uint8(1 << 3)
+	statVBlankIRQ   = uint8(1 << 4)
+	statOAMIRQ      = uint8(1 << 5)
+	statLYCIRQ      = uint8(1 << 6)
 )
 
 // PPU is the Gameboy Picture Processing Unit.
@@ -27,37 +40,104 @@ type PPU struct {
 	Screen  Display
 	Fetcher *Fetcher
 	mem     memory.AddressSpace
+	irq     *interrupts.Controller
+	// model is the hardware model, which selects some behaviours.
+	model model.Model
 
 	// LY Y-Coordinate
 	// https://gbdev.io/pandocs/#ff44-ly-lcdc-y-coordinate-r
 	ly memory.Register
+	// LYC - LY Compare
+	// https://gbdev.io/pandocs/#ff45-lyc-ly-compare-r-w
+	lyc memory.Register
 
-	// SCY - Scroll Y
+	// STAT - LCDC Status
+	// https://gbdev.io/pandocs/#ff41-stat-lcdc-status-r-w
+	stat memory.Register
+	// statLine is the STAT interrupt line, the OR of all the enabled sources.
+	// An interrupt is only requested when it goes from low to high.
+	statLine bool
+
+	// SCY, SCX - Scroll Y, Scroll X
 	// https://gbdev.io/pandocs/#ff42-scy-scroll-y-r-w-ff43-scx-scroll-x-r-w
 	scy memory.Register
+	scx memory.Register
+	// discard is the number of pixels to drop at the start of the line
+	// when SCX isn't a multiple of 8, or of the window when WX is below 7.
+	discard uint8
 
 	// LDCD - LCD Control Register
+	// https://gbdev.io/pandocs/#lcdc-lcd-control
 	lcdcEnabled memory.RegisterBit
+	// bgEnabled shows the background and the window on the DMG. On the CGB,
+	// they're always shown but lose priority over sprites when it's off.
+	bgEnabled memory.RegisterBit
+	// bgMap selects the background map at 0x9C00 instead of 0x9800.
+	bgMap memory.RegisterBit
+	// objEnabled shows sprites.
+	objEnabled memory.RegisterBit
+	// objSize selects 8x16 sprites instead of 8x8.
+	objSize memory.RegisterBit
+
+	// BGP - BG Palette Data
+	// https://gbdev.io/pandocs/#ff47-bgp-bg-palette-data-r-w-non-cgb-mode-only
+	bgp memory.Register
+	// OBP0, OBP1 - Object Palette Data
+	// https://gbdev.io/pandocs/#ff48-obp0-object-palette-0-data-r-w-non-cgb-mode-only
+	obp0 memory.Register
+	obp1 memory.Register
+
+	// sprites are the sprites on the current line, in OAM order
+	// on the CGB and by X on the DMG, which is the fetch order.
+	sprites []sprite
+	// spriteQ holds the sprite pixels to mix with the background.
+	spriteQ spriteFIFO
+	// stall is the number of ticks to wait before outputting more pixels.
+	stall int
 }
 
-// New creates anew PPU.
-func New(m memory.AddressSpace, screen Display) *PPU {
+// New creates anew PPU of the given model reading video memory
+// from mem, owning its registers in io and requesting interrupts to irq.
+func New(m model.Model, mem memory.AddressSpace, io *memory.IO, irq *interrupts.Controller, screen Display) *PPU {
+	regs := io.Raw()
 	return &PPU{
-		Fetcher:     NewFetcher(m),
+		Fetcher:     NewFetcher(mem, regs),
 		Screen:      screen,
 		state:       oamSearch,
-		mem:         m,
-		ly:          memory.NewRegister(m, 0xFF44),
-		scy:         memory.NewRegister(m, 0xFF42),
-		lcdcEnabled: memory.NewRegisterBit(m, 0xFF40, 7),
+		mem:         mem,
+		irq:         irq,
+		model:       m,
+		ly:          memory.NewRegister(regs, 0xFF44),
+		lyc:         memory.NewRegister(regs, 0xFF45),
+		stat:        memory.NewRegister(regs, 0xFF41),
+		scy:         memory.NewRegister(regs, 0xFF42),
+		scx:         memory.NewRegister(regs, 0xFF43),
+		lcdcEnabled: memory.NewRegisterBit(regs, 0xFF40, 7),
+		bgEnabled:   memory.NewRegisterBit(regs, 0xFF40, 0),
+		objEnabled:  memory.NewRegisterBit(regs, 0xFF40, 1),
+		objSize:     memory.NewRegisterBit(regs, 0xFF40, 2),
+		bgMap:       memory.NewRegisterBit(regs, 0xFF40, 3),
+		bgp:         memory.NewRegister(regs, 0xFF47),
+		obp0:        memory.NewRegister(regs, 0xFF48),
+		obp1:        memory.NewRegister(regs, 0xFF49),
+		sprites:     make([]sprite, 0, maxSprites),
 	}
 }
 
 // Tick advances the PPU state by one step.
 func (p *PPU) Tick() {
+	// According to https://gbdev.io/pandocs/#lcdc-7-lcd-display-enable
+	// games should only switch the display off in VBlank, but the
+	// PPU stops immediately anyway.
 	if !p.lcdcEnabled.Get() {
+		if p.Screen.IsEnabled() {
+			p.turnOff()
+		}
 		return
 	}
+	if !p.Screen.IsEnabled() {
+		p.turnOn()
+	}
 
 	p.ticks++
 	switch p.state {
@@ -65,30 +145,35 @@ func (p *PPU) Tick() {
 		// collect sprite data
 		// Here we need to scan the OAM (obj attribute memory)
 		// from 0xFE00 to 0xFE9F to mix sprites with the current line.
-		// This always takes 40 ticks.
-		if p.ticks == 40 {
+		// This always takes 80 ticks, 2 for each entry.
+		if p.ticks%2 == 0 {
+			p.scanOAM(uint8(p.ticks/2 - 1))
+		}
+		if p.ticks == 2*oamEntries {
+			if !p.model.IsCGB() {
+				p.sortSprites()
+			}
 			p.x = 0
+			p.stall = 0
+			p.spriteQ.clear()
 			y := p.scy.Get() + p.ly.Get()
 			tileLine := y % 8
-			tileMapRowAddr := 0x9800 + uint16(y/8)*32
-			p.Fetcher.Start(tileMapRowAddr, tileLine)
-			p.state = pixelTransfer
+			tileMapAddr := uint16(0x9800)
+			if p.bgMap.Get() {
+				tileMapAddr = 0x9C00
+			}
+			tileMapRowAddr := tileMapAddr + uint16(y/8)*32
+			// The line starts from the tile at SCX/8, the first
+			// SCX%8 pixels of which are fetched and dropped.
+			scx := p.scx.Get()
+			p.discard = scx % 8
+			p.Fetcher.LatchWindow(p.ly.Get())
+			p.Fetcher.Start(tileMapRowAddr, scx/8, tileLine)
+			p.setState(pixelTransfer)
 		}
 
 	case pixelTransfer:
-		// Fetch pixel data into the FIFO queue.
-		p.Fetcher.Tick()
-		if p.Fetcher.Q.Size() < 8 {
-			return
-		}
-		// Put a pixel from the FIFO on the screen if we have any.
-		pxColor, _ := p.Fetcher.Q.Pop()
-		p.Screen.Write(pxColor)
-		p.x++
-		if p.x == 160 {
-			p.Screen.HBlank()
-			p.state = hBlank
-		}
+		p.transfer()
 
 	case hBlank:
 		// A full scanline takes 456 ticks to complete. At the end
@@ -99,42 +184,262 @@ func (p *PPU) Tick() {
 			p.ly.Set(p.ly.Get() + 1)
 			if p.ly.Get() == 144 {
 				p.Screen.VBlank()
-				p.state = vBlank
+				p.irq.Request(interrupts.VBlank)
+				p.setState(vBlank)
 			} else {
-				p.state = oamSearch
+				p.setState(oamSearch)
 			}
 		}
 
 	case vBlank:
-		// According to https://gbdev.io/pandocs/#lcdc-7-lcd-display-enable
-		// switching the display on and off can only be done when in VBlank.
-		wasOn := p.Screen.IsEnabled()
-		isOn := p.lcdcEnabled.Get()
-		if wasOn && !isOn {
-			// Turn off.
-			p.Screen.Enable(false)
-			p.Fetcher.Q.Clear()
-			p.x = 0
-			p.ly.Set(0)
-			return
-		}
-		if !wasOn && isOn {
-			// Turn on.
-			p.Fetcher.Q.Clear()
-			p.x = 0
-			p.ly.Set(0)
-			p.Screen.Enable(true)
-			p.state = oamSearch
-			return
-		}
-
+		// VBlank lasts 10 lines, from 144 to 153.
 		if p.ticks == 456 {
 			p.ticks = 0
 			p.ly.Set(p.ly.Get() + 1)
-			if p.ly.Get() == 153 {
+			if p.ly.Get() == 154 {
 				p.ly.Set(0)
-				p.state = oamSearch
+				p.setState(oamSearch)
 			}
 		}
 	}
+	p.updateStat()
+}
+
+// transfer runs a step of the pixel transfer, which outputs a pixel
+// once the FIFO has enough of them.
+func (p *PPU) transfer() {
+	// Wait while sprites are being fetched.
+	if p.stall > 0 {
+		p.stall--
+		return
+	}
+	// Fetch pixel data into the FIFO queue.
+	p.Fetcher.Tick()
+	if p.Fetcher.Q.Size() < 8 {
+		return
+	}
+	// Dropping pixels makes the transfer longer, as on the real hardware.
+	if p.discard > 0 {
+		p.Fetcher.Q.Pop()
+		p.discard--
+		return
+	}
+	// The window replaces the background from its first pixel,
+	// the output waits until the fetcher has filled the queue again.
+	// Its pixels left of the screen are dropped like the SCX%8 ones.
+	if skip, ok := p.Fetcher.StartWindow(p.x); ok {
+		p.discard = skip
+		return
+	}
+	// Sprites starting at this pixel are fetched first.
+	if p.fetchSprites() {
+		return
+	}
+	// Put a pixel from the FIFO on the screen if we have any.
+	bgColor, _ := p.Fetcher.Q.Pop()
+	p.Screen.Write(p.mix(bgColor, p.spriteQ.pop()))
+	p.x++
+	if p.x == 160 {
+		p.Screen.HBlank()
+		p.setState(hBlank)
+	}
+}
+
+// turnOff stops the PPU, which stays on line 0 in HBlank until turned on again.
+func (p *PPU) turnOff() {
+	p.Screen.Enable(false)
+	p.Fetcher.Q.Clear()
+	p.x = 0
+	p.ticks = 0
+	p.ly.Set(0)
+	p.setState(hBlank)
+	p.statLine = false
+}
+
+// turnOn starts the PPU from the beginning of a frame.
+func (p *PPU) turnOn() {
+	p.Fetcher.Q.Clear()
+	p.x = 0
+	p.ticks = 0
+	p.ly.Set(0)
+	p.Screen.Enable(true)
+	p.setState(oamSearch)
+}
+
+// setState changes the state and the mode reported in STAT.
+func (p *PPU) setState(s ppuState) {
+	p.state = s
+	p.stat.Set(p.stat.Get()&^statMode | uint8(s))
+}
+
+// updateStat updates the coincidence flag in STAT and the STAT interrupt line,
+// requesting an interrupt on its rising edge. As the line is shared by all the
+// sources, one going high while another is already high doesn't request a new
+// interrupt: this is known as STAT blocking.
+func (p *PPU) updateStat() {
+	stat := p.stat.Get()
+	if p.ly.Get() == p.lyc.Get() {
+		stat |= statCoincidence
+	} else {
+		stat &^= statCoincidence
+	}
+	p.stat.Set(stat)
+
+	line := stat&statCoincidence != 0 && stat&statLYCIRQ != 0
+	switch p.state {
+	case hBlank:
+		line = line || stat&statHBlankIRQ != 0
+	case vBlank:
+		line = line || stat&statVBlankIRQ != 0
+	case oamSearch:
+		line = line || stat&statOAMIRQ != 0
+	}
+	if line && !p.statLine {
+		p.irq.Request(interrupts.LCDStat)
+	}
+	p.statLine = line
+}
+
+// OAMBlocked returns true while the PPU is reading OAM,
+// i.e. during the OAM search and the pixel transfer.
+func (p *PPU) OAMBlocked() bool {
+	return p.lcdcEnabled.Get() && (p.state == oamSearch || p.state == pixelTransfer)
+}
+
+// spriteHeight returns the height of sprites, 8 or 16 pixels.
+func (p *PPU) spriteHeight() uint8 {
+	if p.objSize.Get() {
+		return 16
+	}
+	return 8
+}
+
+// scanOAM checks whether the i-th sprite in OAM is on the current line
+// and, when there are less than 10, adds it to the sprites to draw.
+func (p *PPU) scanOAM(i uint8) {
+	if i == 0 {
+		p.sprites = p.sprites[:0]
+	}
+	if len(p.sprites) == maxSprites {
+		return
+	}
+	addr := oamAddr + uint16(i)*4
+	y := p.mem.Read(addr)
+	// The sprite Y is the line below the bottom of a 8x16 sprite,
+	// hence lines are offset by 16.
+	line := int(p.ly.Get()) + 16
+	if line < int(y) || line >= int(y)+int(p.spriteHeight()) {
+		return
+	}
+	p.sprites = append(p.sprites, sprite{
+		y:     y,
+		x:     p.mem.Read(addr + 1),
+		tile:  p.mem.Read(addr + 2),
+		attrs: p.mem.Read(addr + 3),
+		index: i,
+	})
+}
+
+// sortSprites sorts the sprites by X, keeping the OAM order of those with
+// the same X. Sprites partially hidden on the left and those starting at the
+// same pixel are fetched together and, on the DMG, the lowest X must win.
+func (p *PPU) sortSprites() {
+	// An insertion sort is stable and doesn't allocate.
+	for i := 1; i < len(p.sprites); i++ {
+		for j := i; j > 0 && p.sprites[j].x < p.sprites[j-1].x; j-- {
+			p.sprites[j], p.sprites[j-1] = p.sprites[j-1], p.sprites[j]
+		}
+	}
+}
+
+// fetchSprites puts the pixels of the sprites starting at
+// the current pixel in the sprite FIFO and returns true when
+// it does so, as the output is then stalled.
+func (p *PPU) fetchSprites() bool {
+	fetched := false
+	for i := range p.sprites {
+		s := &p.sprites[i]
+		// The sprite X is the column after its right edge, hence
+		// columns are offset by 8. Sprites partially hidden on
+		// the left are fetched on the first pixel.
+		if s.fetched || int(s.x) > int(p.x)+8 {
+			continue
+		}
+		s.fetched = true
+		fetched = true
+		p.stall += spriteFetchTicks
+		p.fetchSprite(s, int(p.x)+8-int(s.x))
+	}
+	return fetched
+}
+
+// fetchSprite reads the sprite line and merges its pixels in the
+// sprite FIFO, skipping the first ones which are outside the screen.
+func (p *PPU) fetchSprite(s *sprite, skip int) {
+	height := p.spriteHeight()
+	row := p.ly.Get() + 16 - s.y
+	if s.attrs&attrYFlip != 0 {
+		row = height - 1 - row
+	}
+	tile := s.tile
+	if height == 16 {
+		// The lowest bit of the tile is ignored
+		// and the bottom half uses the next tile.
+		tile &= 0xFE
+		if row >= 8 {
+			tile++
+			row -= 8
+		}
+	}
+	addr := 0x8000 + uint16(tile)*16 + uint16(row)*2
+	low := p.mem.Read(addr)
+	high := p.mem.Read(addr + 1)
+
+	palette := uint8(0)
+	if s.attrs&attrPalette != 0 {
+		palette = 1
+	}
+	for i := skip; i < 8; i++ {
+		// The leftmost pixel is in the most significant bit.
+		bit := uint(7 - i)
+		if s.attrs&attrXFlip != 0 {
+			bit = uint(i)
+		}
+		px := spritePixel{
+			color:      (low>>bit)&1 | ((high>>bit)&1)<<1,
+			palette:    palette,
+			bgPriority: s.attrs&attrBGPriority != 0,
+			index:      s.index,
+		}
+		p.spriteQ.merge(i-skip, px, p.model.IsCGB())
+	}
+}
+
+// mix returns the shade of the pixel combining the background and the sprite.
+// Sprites are drawn over the background unless they're transparent, or they
+// have the BG-over-OBJ attribute and the background color isn't 0.
+func (p *PPU) mix(bg uint8, obj spritePixel) uint8 {
+	if !p.bgEnabled.Get() {
+		if p.model.IsCGB() {
+			obj.bgPriority = false
+		} else {
+			bg = 0
+		}
+	}
+	if obj.color == 0 || !p.objEnabled.Get() || (obj.bgPriority && bg != 0) {
+		return shade(p.bgp.Get(), bg)
+	}
+	if obj.palette == 1 {
+		return shade(p.obp1.Get(), obj.color)
+	}
+	return shade(p.obp0.Get(), obj.color)
+}
+
+// shade returns the shade of a color number in a palette.
+func shade(palette, color uint8) uint8 {
+	// Palettes are 0bAABBCCDD
+	// where the nth pair of bits is the shade for the nth color.
+	// E.g. BGP=0b10110001 and col=0x03 then the shade is 0b10:
+	// (0b10110001 >> 6) & 0x00000011 = 0b00000010 & 0x00000011 = 0b10
+	return palette >> (color * 2) & 0x03
 }
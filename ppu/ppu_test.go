@@ -0,0 +1,489 @@
+package ppu_test
+
+import (
+	"testing"
+
+	"github.com/andreaperizzato/gameboy/interrupts"
+	"github.com/andreaperizzato/gameboy/memory"
+	"github.com/andreaperizzato/gameboy/model"
+	"github.com/andreaperizzato/gameboy/ppu"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder is a display recording the pixels.
+type recorder struct {
+	pixels  []uint8
+	enabled bool
+}
+
+func (r *recorder) Write(color uint8) { r.pixels = append(r.pixels, color) }
+func (r *recorder) HBlank()           {}
+func (r *recorder) VBlank()           {}
+func (r *recorder) Enable(e bool)     { r.enabled = e }
+func (r *recorder) IsEnabled() bool   { return r.enabled }
+
+const (
+	lcdcOn        = uint8(0x80)
+	lcdcBG        = uint8(0x01)
+	lcdcObj       = uint8(0x02)
+	lcdcObj8x16   = uint8(0x04)
+	lcdcBGMap     = uint8(0x08)
+	lcdcTiles8000 = uint8(0x10)
+	lcdcWindow    = uint8(0x20)
+	lcdcWinMap    = uint8(0x40)
+	// lcdcDefault is the value set by the boot ROM.
+	lcdcDefault   = lcdcOn | lcdcBG | lcdcTiles8000
+	lineTicks     = 456
+	screenWidth   = 160
+	attrBGOverOBJ = uint8(0x80)
+	attrYFlip     = uint8(0x40)
+	attrXFlip     = uint8(0x20)
+	attrOBP1      = uint8(0x10)
+)
+
+// render runs the PPU until the given line has been drawn and returns its pixels.
+// The palettes map each color to the shade with the same number, but OBP1
+// which reverses them, and setup can change the memory before starting.
+func render(t *testing.T, m model.Model, lcdc uint8, line int, setup func(mmu *memory.MMU)) []uint8 {
+	return renderLines(t, m, lcdc, line, setup, func(int, *memory.MMU) {})
+}
+
+// renderLines is like render, but calls beforeLine at the start of each line.
+func renderLines(t *testing.T, m model.Model, lcdc uint8, line int, setup func(mmu *memory.MMU), beforeLine func(ly int, mmu *memory.MMU)) []uint8 {
+	p, mmu, _, screen := newPPU(m, lcdc, setup)
+	for ly := 0; ly <= line; ly++ {
+		beforeLine(ly, mmu)
+		for i := 0; i < lineTicks; i++ {
+			p.Tick()
+		}
+	}
+	if !assert.Len(t, screen.pixels, (line+1)*screenWidth) {
+		return nil
+	}
+	return screen.pixels[line*screenWidth:]
+}
+
+// newPPU creates a PPU with its memory, interrupt controller and display.
+func newPPU(m model.Model, lcdc uint8, setup func(mmu *memory.MMU)) (*ppu.PPU, *memory.MMU, *interrupts.Controller, *recorder) {
+	memmap := memory.NewDMGMap(m, memory.NewROM(nil, 0))
+	irq := interrupts.New()
+	mmu := memory.NewMMU(memory.NewROM(nil, 0), append([]memory.AddressSpace{irq}, memmap.Spaces()...)...)
+	mmu.Write(0xFF40, lcdc)
+	mmu.Write(0xFF47, 0xE4)
+	mmu.Write(0xFF48, 0xE4)
+	mmu.Write(0xFF49, 0x1B)
+	setup(mmu)
+
+	screen := &recorder{}
+	return ppu.New(m, mmu, memmap.IO, irq, screen), mmu, irq, screen
+}
+
+// setSprite writes the i-th sprite in OAM.
+func setSprite(mmu *memory.MMU, i int, y, x, tile, attrs uint8) {
+	addr := 0xFE00 + uint16(i)*4
+	mmu.Write(addr, y)
+	mmu.Write(addr+1, x)
+	mmu.Write(addr+2, tile)
+	mmu.Write(addr+3, attrs)
+}
+
+// setTileRow writes a row of a tile where all pixels have the given color,
+// but the leftmost one which has color 1.
+func setTileRow(mmu *memory.MMU, tile uint8, row int, color uint8) {
+	addr := 0x8000 + uint16(tile)*16 + uint16(row)*2
+	low, high := uint8(0x00), uint8(0x00)
+	if color&1 != 0 {
+		low = 0x7F
+	}
+	if color&2 != 0 {
+		high = 0x7F
+	}
+	mmu.Write(addr, low|0x80)
+	mmu.Write(addr+1, high)
+}
+
+func TestPPU_Sprite(t *testing.T) {
+	tests := []struct {
+		name  string
+		lcdc  uint8
+		attrs uint8
+		line  int
+		// exp are the pixels from 8 to 20.
+		exp []uint8
+	}{
+		{"sprite", lcdcObj, 0, 0, []uint8{0, 0, 1, 3, 3, 3, 3, 3, 3, 3, 0, 0}},
+		{"sprites disabled", 0, 0, 0, []uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"x flip", lcdcObj, attrXFlip, 0, []uint8{0, 0, 3, 3, 3, 3, 3, 3, 3, 1, 0, 0}},
+		{"y flip", lcdcObj, attrYFlip, 0, []uint8{0, 0, 1, 2, 2, 2, 2, 2, 2, 2, 0, 0}},
+		{"OBP1", lcdcObj, attrOBP1, 0, []uint8{0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"bottom line", lcdcObj, 0, 7, []uint8{0, 0, 1, 2, 2, 2, 2, 2, 2, 2, 0, 0}},
+		{"below 8x8", lcdcObj, 0, 8, []uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"8x16 top", lcdcObj | lcdcObj8x16, 0, 0, []uint8{0, 0, 1, 1, 1, 1, 1, 1, 1, 1, 0, 0}},
+		{"8x16 bottom", lcdcObj | lcdcObj8x16, 0, 8, []uint8{0, 0, 1, 3, 3, 3, 3, 3, 3, 3, 0, 0}},
+		{"8x16 y flip", lcdcObj | lcdcObj8x16, attrYFlip, 0, []uint8{0, 0, 1, 2, 2, 2, 2, 2, 2, 2, 0, 0}},
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			line := render(t, model.DMG, lcdcDefault|tC.lcdc, tC.line, func(mmu *memory.MMU) {
+				// The sprite uses tile 3, or tiles 2 and 3 in 8x16 mode.
+				setTileRow(mmu, 2, 0, 1)
+				setTileRow(mmu, 3, 0, 3)
+				setTileRow(mmu, 3, 7, 2)
+				setSprite(mmu, 0, 16, 18, 3, tC.attrs)
+			})
+			assert.Equal(t, tC.exp, line[8:20])
+		})
+	}
+}
+
+func TestPPU_SpriteBGPriority(t *testing.T) {
+	line := render(t, model.DMG, lcdcDefault|lcdcObj, 0, func(mmu *memory.MMU) {
+		// The background has color 1 on the first pixel of each tile.
+		setTileRow(mmu, 0, 0, 0)
+		setTileRow(mmu, 1, 0, 3)
+		setSprite(mmu, 0, 16, 8, 1, attrBGOverOBJ)
+	})
+	assert.Equal(t, []uint8{1, 3, 3, 3, 3, 3, 3, 3, 1, 0}, line[:10])
+}
+
+func TestPPU_SpriteLeftEdge(t *testing.T) {
+	line := render(t, model.DMG, lcdcDefault|lcdcObj, 0, func(mmu *memory.MMU) {
+		setTileRow(mmu, 1, 0, 2)
+		setSprite(mmu, 0, 16, 3, 1, 0)
+	})
+	assert.Equal(t, []uint8{2, 2, 2, 0}, line[:4])
+}
+
+func TestPPU_SpritesPerLine(t *testing.T) {
+	line := render(t, model.DMG, lcdcDefault|lcdcObj, 0, func(mmu *memory.MMU) {
+		setTileRow(mmu, 1, 0, 3)
+		// 11 sprites, 10 pixels apart: only the first 10 in OAM are drawn.
+		for i := 0; i < 11; i++ {
+			setSprite(mmu, i, 16, uint8(8+10*i), 1, 0)
+		}
+	})
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, uint8(3), line[10*i+1], "sprite %d", i)
+	}
+	assert.Equal(t, uint8(0), line[101], "sprite 10")
+}
+
+func TestPPU_SpritePriority(t *testing.T) {
+	tests := []struct {
+		name  string
+		model model.Model
+		exp   []uint8
+	}{
+		// The sprite more on the left wins.
+		{"DMG", model.DMG, []uint8{1, 3, 3, 3, 3, 3, 3, 3, 2, 2}},
+		// The first sprite in OAM wins.
+		{"CGB", model.CGB, []uint8{1, 3, 3, 3, 1, 2, 2, 2, 2, 2}},
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			line := render(t, tC.model, lcdcDefault|lcdcObj, 0, func(mmu *memory.MMU) {
+				setTileRow(mmu, 1, 0, 2)
+				setTileRow(mmu, 2, 0, 3)
+				setSprite(mmu, 0, 16, 12, 1, 0)
+				setSprite(mmu, 1, 16, 8, 2, 0)
+			})
+			assert.Equal(t, tC.exp, line[:10])
+		})
+	}
+}
+
+func TestPPU_SpriteLeftEdgePriority(t *testing.T) {
+	tests := []struct {
+		name  string
+		model model.Model
+		exp   []uint8
+	}{
+		// Both are fetched on the first pixel, the one more on the left wins.
+		{"DMG", model.DMG, []uint8{3, 3, 2, 2, 2, 0}},
+		// The first sprite in OAM wins.
+		{"CGB", model.CGB, []uint8{2, 2, 2, 2, 2, 0}},
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			line := render(t, tC.model, lcdcDefault|lcdcObj, 0, func(mmu *memory.MMU) {
+				setTileRow(mmu, 1, 0, 2)
+				setTileRow(mmu, 2, 0, 3)
+				setSprite(mmu, 0, 16, 5, 1, 0)
+				setSprite(mmu, 1, 16, 2, 2, 0)
+			})
+			assert.Equal(t, tC.exp, line[:6])
+		})
+	}
+}
+
+// setWindow fills the window map at 0x9C00 with tile 1, where each row
+// has the color of its number modulo 4, and sets WY and WX.
+func setWindow(mmu *memory.MMU, wy, wx uint8) {
+	for i := uint16(0); i < 0x400; i++ {
+		mmu.Write(0x9C00+i, 1)
+	}
+	for row := 0; row < 8; row++ {
+		setTileRow(mmu, 1, row, uint8(row%4))
+	}
+	mmu.Write(0xFF4A, wy)
+	mmu.Write(0xFF4B, wx)
+}
+
+func TestPPU_Window(t *testing.T) {
+	tests := []struct {
+		name string
+		lcdc uint8
+		wy   uint8
+		wx   uint8
+		line int
+		// exp are the pixels from 78 to 90.
+		exp []uint8
+	}{
+		{"window", lcdcWindow | lcdcWinMap, 0, 87, 0, []uint8{0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 0}},
+		{"window line", lcdcWindow | lcdcWinMap, 0, 87, 3, []uint8{0, 0, 1, 3, 3, 3, 3, 3, 3, 3, 1, 3}},
+		{"disabled", lcdcWinMap, 0, 87, 3, []uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"above WY", lcdcWindow | lcdcWinMap, 4, 87, 3, []uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"below WY", lcdcWindow | lcdcWinMap, 2, 87, 3, []uint8{0, 0, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}},
+		{"left edge", lcdcWindow | lcdcWinMap, 0, 7, 1, []uint8{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}},
+		{"right of the screen", lcdcWindow | lcdcWinMap, 0, 167, 1, []uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		// Without bit 6, the window shares the background map.
+		{"BG map", lcdcWindow, 0, 87, 1, []uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			line := render(t, model.DMG, lcdcDefault|tC.lcdc, tC.line, func(mmu *memory.MMU) {
+				setWindow(mmu, tC.wy, tC.wx)
+			})
+			assert.Equal(t, tC.exp, line[78:90])
+		})
+	}
+}
+
+func TestPPU_WindowLeftOfScreen(t *testing.T) {
+	// With WX=3, the first 4 pixels of the window are hidden.
+	line := render(t, model.DMG, lcdcDefault|lcdcWindow|lcdcWinMap, 2, func(mmu *memory.MMU) {
+		setWindow(mmu, 0, 3)
+	})
+	assert.Equal(t, []uint8{2, 2, 2, 2, 1, 2, 2, 2, 2, 2, 2, 2, 1, 2}, line[:14])
+}
+
+func TestPPU_WindowLineCounter(t *testing.T) {
+	// The window is hidden on lines 1 and 2, so line 3 draws its second line.
+	line := renderLines(t, model.DMG, lcdcDefault|lcdcWindow|lcdcWinMap, 3, func(mmu *memory.MMU) {
+		setWindow(mmu, 0, 87)
+	}, func(ly int, mmu *memory.MMU) {
+		wx := uint8(87)
+		if ly == 1 || ly == 2 {
+			wx = 200
+		}
+		mmu.Write(0xFF4B, wx)
+	})
+	assert.Equal(t, []uint8{0, 0, 1, 1}, line[78:82])
+}
+
+func TestPPU_WindowLatchesWY(t *testing.T) {
+	// WY is only compared with LY at the start of each line,
+	// changing it later in the frame doesn't hide the window.
+	line := renderLines(t, model.DMG, lcdcDefault|lcdcWindow|lcdcWinMap, 2, func(mmu *memory.MMU) {
+		setWindow(mmu, 1, 87)
+	}, func(ly int, mmu *memory.MMU) {
+		if ly == 2 {
+			mmu.Write(0xFF4A, 100)
+		}
+	})
+	assert.Equal(t, []uint8{0, 0, 1, 1}, line[78:82])
+}
+
+func TestPPU_ScrollX(t *testing.T) {
+	tests := []struct {
+		name string
+		scx  uint8
+		// exp are the first 12 pixels.
+		exp []uint8
+	}{
+		{"no scroll", 0, []uint8{0, 0, 0, 0, 0, 0, 0, 0, 1, 3, 3, 3}},
+		{"tile", 8, []uint8{1, 3, 3, 3, 3, 3, 3, 3, 0, 0, 0, 0}},
+		{"fine", 3, []uint8{0, 0, 0, 0, 0, 1, 3, 3, 3, 3, 3, 3}},
+		{"tile and fine", 13, []uint8{3, 3, 3, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"wrap around", 253, []uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}},
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			line := render(t, model.DMG, lcdcDefault, 0, func(mmu *memory.MMU) {
+				// Only the second tile of the map row isn't blank.
+				setTileRow(mmu, 1, 0, 3)
+				mmu.Write(0x9801, 1)
+				mmu.Write(0xFF43, tC.scx)
+			})
+			assert.Equal(t, tC.exp, line[:12])
+		})
+	}
+}
+
+func TestPPU_LCDC(t *testing.T) {
+	tests := []struct {
+		name string
+		lcdc uint8
+		// exp are the first 9 pixels.
+		exp []uint8
+	}{
+		{"default", lcdcDefault, []uint8{1, 3, 3, 3, 3, 3, 3, 3, 0}},
+		{"BG disabled", lcdcOn | lcdcTiles8000, []uint8{0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"BG map", lcdcDefault | lcdcBGMap, []uint8{1, 2, 2, 2, 2, 2, 2, 2, 0}},
+		{"signed tiles", lcdcOn | lcdcBG, []uint8{1, 1, 1, 1, 1, 1, 1, 1, 0}},
+		{"signed tiles negative", lcdcOn | lcdcBG | lcdcBGMap, []uint8{1, 2, 2, 2, 2, 2, 2, 2, 0}},
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			line := render(t, model.DMG, tC.lcdc, 0, func(mmu *memory.MMU) {
+				// Map 0x9800 uses tile 1 and map 0x9C00 tile 0xFF,
+				// both in the 0x8000 and 0x8800 tile data.
+				mmu.Write(0x9800, 0x01)
+				mmu.Write(0x9C00, 0xFF)
+				setTileRow(mmu, 0x01, 0, 3)
+				setTileRow(mmu, 0xFF, 0, 2)
+				mmu.Write(0x9010, 0xFF)
+				mmu.Write(0x8FF0, 0x80)
+				mmu.Write(0x8FF1, 0x7F)
+				// Tile 0 in the 0x8800 tile data is blank.
+				for i := uint16(0); i < 16; i++ {
+					mmu.Write(0x9000+i, 0)
+				}
+			})
+			assert.Equal(t, tC.exp, line[:9])
+		})
+	}
+}
+
+func TestPPU_BGDisabledSpritePriority(t *testing.T) {
+	tests := []struct {
+		name  string
+		model model.Model
+		exp   []uint8
+	}{
+		// The background is blank, so the sprite is visible.
+		{"DMG", model.DMG, []uint8{3, 3, 0}},
+		// The background is still drawn, but the sprite is over it.
+		{"CGB", model.CGB, []uint8{3, 3, 1}},
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			line := render(t, tC.model, lcdcOn|lcdcTiles8000|lcdcObj, 0, func(mmu *memory.MMU) {
+				// The background has color 1.
+				setTileRow(mmu, 0, 0, 1)
+				// Only the first 2 pixels of the sprite aren't transparent.
+				mmu.Write(0x8010, 0xC0)
+				mmu.Write(0x8011, 0xC0)
+				setSprite(mmu, 0, 16, 8, 1, attrBGOverOBJ)
+			})
+			assert.Equal(t, tC.exp, line[:3])
+		})
+	}
+}
+
+// tick runs the PPU for n ticks.
+func tick(p *ppu.PPU, n int) {
+	for i := 0; i < n; i++ {
+		p.Tick()
+	}
+}
+
+func TestPPU_STATMode(t *testing.T) {
+	p, mmu, _, _ := newPPU(model.DMG, lcdcDefault, func(*memory.MMU) {})
+	mode := func() uint8 { return mmu.Read(0xFF41) & 0x03 }
+
+	tick(p, 1)
+	assert.Equal(t, uint8(2), mode(), "OAM search")
+	tick(p, 79)
+	assert.Equal(t, uint8(3), mode(), "pixel transfer")
+	tick(p, 200)
+	assert.Equal(t, uint8(0), mode(), "HBlank")
+	tick(p, 176)
+	assert.Equal(t, uint8(2), mode(), "next line")
+	assert.Equal(t, uint8(1), mmu.Read(0xFF44), "LY")
+
+	tick(p, 143*lineTicks)
+	assert.Equal(t, uint8(1), mode(), "VBlank")
+	assert.Equal(t, uint8(144), mmu.Read(0xFF44), "LY")
+	tick(p, 9*lineTicks)
+	assert.Equal(t, uint8(1), mode(), "last line")
+	assert.Equal(t, uint8(153), mmu.Read(0xFF44), "LY")
+	tick(p, lineTicks)
+	assert.Equal(t, uint8(2), mode(), "next frame")
+	assert.Equal(t, uint8(0), mmu.Read(0xFF44), "LY")
+
+	mmu.Write(0xFF40, 0x00)
+	tick(p, 1)
+	assert.Equal(t, uint8(0), mode(), "LCD off")
+	assert.Equal(t, uint8(0), mmu.Read(0xFF44), "LY")
+	assert.False(t, p.OAMBlocked(), "OAM access")
+}
+
+func TestPPU_VBlankInterrupt(t *testing.T) {
+	p, _, irq, _ := newPPU(model.DMG, lcdcDefault, func(*memory.MMU) {})
+
+	tick(p, 144*lineTicks-1)
+	assert.False(t, irq.Requested(interrupts.VBlank), "line 143")
+	tick(p, 1)
+	assert.True(t, irq.Requested(interrupts.VBlank), "line 144")
+	assert.False(t, irq.Requested(interrupts.LCDStat), "STAT")
+}
+
+func TestPPU_Coincidence(t *testing.T) {
+	p, mmu, irq, _ := newPPU(model.DMG, lcdcDefault, func(mmu *memory.MMU) {
+		mmu.Write(0xFF45, 2)
+		mmu.Write(0xFF41, 0x40)
+	})
+
+	tick(p, 2*lineTicks-1)
+	assert.Equal(t, uint8(0), mmu.Read(0xFF41)&0x04, "line 1")
+	assert.False(t, irq.Requested(interrupts.LCDStat), "line 1")
+	tick(p, 1)
+	assert.Equal(t, uint8(0x04), mmu.Read(0xFF41)&0x04, "line 2")
+	assert.True(t, irq.Requested(interrupts.LCDStat), "line 2")
+	tick(p, lineTicks)
+	assert.Equal(t, uint8(0), mmu.Read(0xFF41)&0x04, "line 3")
+}
+
+func TestPPU_STATInterrupt(t *testing.T) {
+	tests := []struct {
+		name   string
+		enable uint8
+		// exp are the lines where an interrupt is requested
+		// in the first 4 lines and at the start of VBlank.
+		exp []int
+	}{
+		{"none", 0x00, nil},
+		{"HBlank", 0x08, []int{0, 1, 2, 3}},
+		{"VBlank", 0x10, []int{144}},
+		{"OAM", 0x20, []int{0, 1, 2, 3}},
+		{"LYC", 0x40, []int{1}},
+		// The line stays high from the HBlank of line 0 to that of line 1.
+		{"HBlank and LYC", 0x48, []int{0, 2, 3}},
+		// The line stays high from line 1 to the OAM search of line 2.
+		{"OAM and LYC", 0x60, []int{0, 1, 3}},
+		// The line only goes low in pixel transfer, so there's an interrupt
+		// at each HBlank but not at the OAM search following it.
+		{"HBlank and OAM", 0x28, []int{0, 0, 1, 2, 3}},
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			p, _, irq, _ := newPPU(model.DMG, lcdcDefault, func(mmu *memory.MMU) {
+				mmu.Write(0xFF45, 1)
+				mmu.Write(0xFF41, tC.enable)
+			})
+			var requested []int
+			for i := 1; i <= 145*lineTicks; i++ {
+				p.Tick()
+				if irq.Requested(interrupts.LCDStat) {
+					irq.Clear(interrupts.LCDStat)
+					// A line starts after 456 ticks.
+					if line := i / lineTicks; line < 4 || line == 144 {
+						requested = append(requested, line)
+					}
+				}
+			}
+			assert.Equal(t, tC.exp, requested)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package ppu
+
+// More info about sprites can be found here:
+// https://gbdev.io/pandocs/#vram-sprite-attribute-table-oam
+// https://gbdev.io/pandocs/#sprite-priorities-and-conflicts
+
+const (
+	oamAddr = uint16(0xFE00)
+	// oamEntries is the number of sprites in OAM, each one taking 4 bytes.
+	oamEntries = 40
+	// maxSprites is the maximum number of sprites on a line.
+	maxSprites = 10
+	// spriteFetchTicks is the number of ticks the pixel output waits
+	// while a sprite is fetched. It's 6 to 11 on the real hardware.
+	spriteFetchTicks = 6
+)
+
+// Bits of the sprite attributes.
+const (
+	attrBGPriority = uint8(1 << 7)
+	attrYFlip      = uint8(1 << 6)
+	attrXFlip      = uint8(1 << 5)
+	attrPalette    = uint8(1 << 4)
+)
+
+// sprite is an object selected during the OAM search.
+type sprite struct {
+	// y and x are the position of the bottom right corner
+	// of a 8x16 sprite, i.e. (16, 8) is the top left of the screen.
+	y     uint8
+	x     uint8
+	tile  uint8
+	attrs uint8
+	// index is the position in OAM.
+	index uint8
+	// fetched is true once its pixels are in the FIFO.
+	fetched bool
+}
+
+// spritePixel is a pixel in the sprite FIFO.
+type spritePixel struct {
+	// color is the color number, 0 is transparent.
+	color uint8
+	// palette is 0 for OBP0 and 1 for OBP1.
+	palette uint8
+	// bgPriority is true when background colors 1-3 are drawn over it.
+	bgPriority bool
+	// index is the position in OAM of the sprite.
+	index uint8
+}
+
+// spriteFIFO holds the sprite pixels to mix with the next background ones.
+// Unlike the background FIFO, it always has 8 pixels, the transparent
+// ones being those where no sprite has been fetched.
+type spriteFIFO struct {
+	pixels [8]spritePixel
+}
+
+// pop returns the first pixel.
+func (q *spriteFIFO) pop() spritePixel {
+	px := q.pixels[0]
+	copy(q.pixels[:], q.pixels[1:])
+	q.pixels[len(q.pixels)-1] = spritePixel{}
+	return px
+}
+
+// merge puts a pixel in the i-th position if it has priority over the current one.
+// On the DMG, the sprite fetched first, which has the lowest X, wins and so the
+// pixel only replaces a transparent one. On the CGB, the lowest OAM index wins.
+func (q *spriteFIFO) merge(i int, px spritePixel, cgb bool) {
+	curr := &q.pixels[i]
+	if curr.color == 0 || (cgb && px.color != 0 && px.index < curr.index) {
+		*curr = px
+	}
+}
+
+// clear removes all the pixels.
+func (q *spriteFIFO) clear() {
+	q.pixels = [8]spritePixel{}
+}
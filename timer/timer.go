@@ -0,0 +1,176 @@
+package timer
+
+import (
+	"github.com/andreaperizzato/gameboy/interrupts"
+	"github.com/andreaperizzato/gameboy/model"
+)
+
+// More info about the timer can be found here:
+// https://gbdev.io/pandocs/#timer-and-divider-registers
+// https://gbdev.io/pandocs/#timer-obscure-behaviour
+
+const (
+	// DIV - Divider Register
+	divAddr = uint16(0xFF04)
+	// TIMA - Timer counter
+	timaAddr = uint16(0xFF05)
+	// TMA - Timer Modulo
+	tmaAddr = uint16(0xFF06)
+	// TAC - Timer Control
+	tacAddr = uint16(0xFF07)
+
+	tacEnable = uint8(1 << 2)
+	// Only the lowest 3 bits of TAC are used, the others always read 1.
+	tacMask = uint8(0x07)
+
+	// After overflowing, TIMA stays 0 for 4 cycles before being reloaded.
+	reloadDelay = 4
+)
+
+// tacBits maps the clock select of TAC to the bit of the internal
+// divider whose falling edge increments TIMA.
+// 00: 4096Hz, 01: 262144Hz, 10: 65536Hz, 11: 16384Hz.
+var tacBits = [4]uint{9, 3, 5, 7}
+
+// Timer emulates the divider and the timer.
+// It is an address space mapping the DIV, TIMA, TMA and TAC registers.
+type Timer struct {
+	irq *interrupts.Controller
+
+	// div is the internal 16-bit counter incremented at every tick.
+	// DIV is its high byte.
+	div  uint16
+	tima uint8
+	tma  uint8
+	tac  uint8
+
+	// reload counts the ticks left before TIMA is reloaded
+	// after an overflow, it's 0 when no reload is scheduled.
+	reload uint8
+}
+
+// New creates a new timer.
+func New(irq *interrupts.Controller) *Timer {
+	return &Timer{
+		irq: irq,
+	}
+}
+
+// Tick advances the timer by one clock cycle.
+func (t *Timer) Tick() {
+	if t.reload > 0 {
+		t.reload--
+		if t.reload == 0 {
+			t.tima = t.tma
+			t.irq.Request(interrupts.Timer)
+		}
+	}
+	t.setDiv(t.div + 1)
+}
+
+// postBootDiv is the internal counter when the boot ROM of each model ends.
+// The SGB and CGB boot ROMs don't always take the same time, so those are typical values.
+var postBootDiv = map[model.Model]uint16{
+	model.DMG0: 0x1830,
+	model.DMG:  0xABCC,
+	model.MGB:  0xABCC,
+	model.SGB:  0xD85C,
+	model.CGB:  0x1EA0,
+	model.AGB:  0x1EA0,
+}
+
+// SkipBoot sets the timer as the boot ROM of the model leaves it.
+func (t *Timer) SkipBoot(m model.Model) {
+	t.div = postBootDiv[m]
+	t.tima, t.tma, t.tac, t.reload = 0, 0, 0, 0
+}
+
+// Contains returns true when the address is part of the address space.
+func (t *Timer) Contains(addr uint16) bool {
+	return addr >= divAddr && addr <= tacAddr
+}
+
+// Read returns the byte at the given address.
+func (t *Timer) Read(addr uint16) uint8 {
+	switch addr {
+	case divAddr:
+		return uint8(t.div >> 8)
+	case timaAddr:
+		return t.tima
+	case tmaAddr:
+		return t.tma
+	default:
+		return t.tac | ^tacMask
+	}
+}
+
+// Write writes a value at the given address.
+func (t *Timer) Write(addr uint16, v uint8) {
+	switch addr {
+	case divAddr:
+		// Writing any value resets the whole internal counter.
+		t.setDiv(0)
+	case timaAddr:
+		// Writing TIMA while waiting for the reload cancels it.
+		t.tima = v
+		t.reload = 0
+	case tmaAddr:
+		t.tma = v
+	default:
+		old := t.signal()
+		t.tac = v & tacMask
+		// Disabling the timer or changing the clock may
+		// cause a falling edge and increment TIMA.
+		if old && !t.signal() {
+			t.increment()
+		}
+	}
+}
+
+// Peek returns the byte at the given address.
+func (t *Timer) Peek(addr uint16) uint8 {
+	return t.Read(addr)
+}
+
+// Poke sets the register at the given address without resetting
+// the divider, cancelling the reload or incrementing TIMA.
+func (t *Timer) Poke(addr uint16, v uint8) {
+	switch addr {
+	case divAddr:
+		t.div = uint16(v)<<8 | t.div&0xFF
+	case timaAddr:
+		t.tima = v
+	case tmaAddr:
+		t.tma = v
+	default:
+		t.tac = v & tacMask
+	}
+}
+
+// setDiv sets the internal counter and increments TIMA
+// when that causes a falling edge on the selected bit.
+// This is also the reason why resetting DIV can increment TIMA.
+func (t *Timer) setDiv(v uint16) {
+	old := t.signal()
+	t.div = v
+	if old && !t.signal() {
+		t.increment()
+	}
+}
+
+// signal returns the input of the falling edge detector,
+// which is the selected bit of the divider when the timer is enabled.
+func (t *Timer) signal() bool {
+	if t.tac&tacEnable == 0 {
+		return false
+	}
+	bit := tacBits[t.tac&0x03]
+	return (t.div>>bit)&1 == 1
+}
+
+func (t *Timer) increment() {
+	t.tima++
+	if t.tima == 0 {
+		t.reload = reloadDelay
+	}
+}
@@ -0,0 +1,146 @@
+package timer_test
+
+import (
+	"testing"
+
+	"github.com/andreaperizzato/gameboy/interrupts"
+	"github.com/andreaperizzato/gameboy/model"
+	"github.com/andreaperizzato/gameboy/timer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimer_DIV(t *testing.T) {
+	tmr := timer.New(interrupts.New())
+	tick(tmr, 255)
+	assert.Equal(t, uint8(0x00), tmr.Read(0xFF04))
+	tick(tmr, 1)
+	assert.Equal(t, uint8(0x01), tmr.Read(0xFF04))
+
+	// Writing any value resets it.
+	tmr.Write(0xFF04, 0xAB)
+	assert.Equal(t, uint8(0x00), tmr.Read(0xFF04))
+}
+
+func TestTimer_TIMAFrequencies(t *testing.T) {
+	tests := []struct {
+		name   string
+		tac    uint8
+		period int
+	}{
+		{"4096Hz", 0x04, 1024},
+		{"262144Hz", 0x05, 16},
+		{"65536Hz", 0x06, 64},
+		{"16384Hz", 0x07, 256},
+	}
+	for _, tC := range tests {
+		t.Run(tC.name, func(t *testing.T) {
+			tmr := timer.New(interrupts.New())
+			tmr.Write(0xFF07, tC.tac)
+
+			tick(tmr, tC.period-1)
+			assert.Equal(t, uint8(0x00), tmr.Read(0xFF05), "before a period")
+			tick(tmr, 1)
+			assert.Equal(t, uint8(0x01), tmr.Read(0xFF05), "after a period")
+			tick(tmr, tC.period*2)
+			assert.Equal(t, uint8(0x03), tmr.Read(0xFF05), "after three periods")
+		})
+	}
+}
+
+func TestTimer_Disabled(t *testing.T) {
+	tmr := timer.New(interrupts.New())
+	tmr.Write(0xFF07, 0x01)
+	tick(tmr, 1024)
+	assert.Equal(t, uint8(0x00), tmr.Read(0xFF05))
+	assert.Equal(t, uint8(0xF9), tmr.Read(0xFF07), "unused bits of TAC read 1")
+}
+
+func TestTimer_Overflow(t *testing.T) {
+	irq := interrupts.New()
+	tmr := timer.New(irq)
+	tmr.Write(0xFF06, 0xAA)
+	tmr.Write(0xFF05, 0xFF)
+	tmr.Write(0xFF07, 0x05)
+
+	tick(tmr, 16)
+	// TIMA stays 0 for a machine cycle before being reloaded.
+	assert.Equal(t, uint8(0x00), tmr.Read(0xFF05), "TIMA after overflow")
+	assert.False(t, irq.Requested(interrupts.Timer), "interrupt after overflow")
+	tick(tmr, 3)
+	assert.Equal(t, uint8(0x00), tmr.Read(0xFF05), "TIMA during reload")
+	tick(tmr, 1)
+	assert.Equal(t, uint8(0xAA), tmr.Read(0xFF05), "TIMA after reload")
+	assert.True(t, irq.Requested(interrupts.Timer), "interrupt after reload")
+}
+
+func TestTimer_OverflowCancelled(t *testing.T) {
+	irq := interrupts.New()
+	tmr := timer.New(irq)
+	tmr.Write(0xFF06, 0xAA)
+	tmr.Write(0xFF05, 0xFF)
+	tmr.Write(0xFF07, 0x05)
+
+	tick(tmr, 16)
+	tmr.Write(0xFF05, 0x11)
+	tick(tmr, 4)
+	assert.Equal(t, uint8(0x11), tmr.Read(0xFF05), "TIMA")
+	assert.False(t, irq.Requested(interrupts.Timer), "interrupt")
+}
+
+func TestTimer_DIVWriteGlitch(t *testing.T) {
+	tmr := timer.New(interrupts.New())
+	tmr.Write(0xFF07, 0x05)
+
+	// Bit 3 of the divider is set after 8 ticks: resetting
+	// DIV causes a falling edge which increments TIMA.
+	tick(tmr, 8)
+	tmr.Write(0xFF04, 0x00)
+	assert.Equal(t, uint8(0x01), tmr.Read(0xFF05), "TIMA")
+
+	// No falling edge when the bit is not set.
+	tick(tmr, 7)
+	tmr.Write(0xFF04, 0x00)
+	assert.Equal(t, uint8(0x01), tmr.Read(0xFF05), "TIMA")
+}
+
+func TestTimer_TACWriteGlitch(t *testing.T) {
+	tmr := timer.New(interrupts.New())
+	tmr.Write(0xFF07, 0x05)
+
+	// Disabling the timer while the selected bit is set increments TIMA.
+	tick(tmr, 8)
+	tmr.Write(0xFF07, 0x01)
+	assert.Equal(t, uint8(0x01), tmr.Read(0xFF05), "TIMA")
+}
+
+func tick(tmr *timer.Timer, times int) {
+	for i := 0; i < times; i++ {
+		tmr.Tick()
+	}
+}
+
+func TestTimer_Poke(t *testing.T) {
+	irq := interrupts.New()
+	tmr := timer.New(irq)
+	tmr.Write(0xFF07, 0x05)
+	tick(tmr, 8)
+
+	// Poking doesn't cause the side effects of writing.
+	tmr.Poke(0xFF04, 0x12)
+	assert.Equal(t, uint8(0x12), tmr.Peek(0xFF04), "DIV")
+	tmr.Poke(0xFF07, 0x00)
+	assert.Equal(t, uint8(0x00), tmr.Peek(0xFF05), "no TIMA increment")
+}
+
+func TestTimer_SkipBoot(t *testing.T) {
+	tmr := timer.New(interrupts.New())
+	tmr.SkipBoot(model.DMG)
+	assert.Equal(t, uint8(0xAB), tmr.Read(0xFF04), "DIV")
+	assert.Equal(t, uint8(0xF8), tmr.Read(0xFF07), "TAC")
+
+	// The low byte of the counter is kept too.
+	tick(tmr, 0x33)
+	assert.Equal(t, uint8(0xAB), tmr.Read(0xFF04), "DIV")
+	tick(tmr, 1)
+	assert.Equal(t, uint8(0xAC), tmr.Read(0xFF04), "DIV")
+}